@@ -27,6 +27,13 @@ func (b *WheelBucket) addTimeout(timeout *WheelTimeout) {
 }
 
 func (b *WheelBucket) remove(timeout *WheelTimeout) *WheelTimeout {
+	next := b.unlink(timeout)
+	timeout.timer.pendingTimeouts.Add(-1)
+	return next
+}
+
+// unlink removes the timeout from the bucket without releasing it, so that it can be placed into another bucket.
+func (b *WheelBucket) unlink(timeout *WheelTimeout) *WheelTimeout {
 	next := timeout.next
 	if timeout.prev != nil {
 		timeout.prev.next = next
@@ -48,7 +55,6 @@ func (b *WheelBucket) remove(timeout *WheelTimeout) *WheelTimeout {
 	timeout.prev = nil
 	timeout.next = nil
 	timeout.bucket = nil
-	timeout.timer.pendingTimeouts.Add(-1)
 	return next
 }
 
@@ -57,17 +63,22 @@ func (b *WheelBucket) expireTimeouts(deadline time.Duration) {
 
 	for timeout != nil {
 		next := timeout.next
+		if timeout.IsCancelled() {
+			// cancelled timeouts are released by processCancelledTasks, removing them here as well
+			// would release them twice.
+			timeout = next
+			continue
+		}
+
 		if timeout.remainingRounds <= 0 {
 			next = b.remove(timeout)
-			if timeout.deadline <= deadline {
+			if timeout.getDeadline() <= deadline {
 				timeout.Expired()
 			} else {
 				// The timeout was placed into a wrong slot. This should never happen.
-				err := fmt.Errorf("timeout.deadline(%d) > deadline(%d)", timeout.deadline, deadline)
+				err := fmt.Errorf("timeout.deadline(%d) > deadline(%d)", timeout.getDeadline(), deadline)
 				panic(err)
 			}
-		} else if timeout.IsCancelled() {
-			next = b.remove(timeout)
 		} else {
 			timeout.remainingRounds--
 		}
@@ -88,6 +99,22 @@ func (b *WheelBucket) clearTimeouts(unprocessedTimeouts []*WheelTimeout) []*Whee
 	}
 }
 
+// drainTimeouts removes all timeouts from the bucket and passes them to f. Timeouts which are added back to the
+// bucket by f are kept for later.
+func (b *WheelBucket) drainTimeouts(f func(*WheelTimeout)) {
+	timeout := b.head
+	b.head = nil
+	b.tail = nil
+	for timeout != nil {
+		next := timeout.next
+		timeout.prev = nil
+		timeout.next = nil
+		timeout.bucket = nil
+		f(timeout)
+		timeout = next
+	}
+}
+
 func (b *WheelBucket) pollTimeout() *WheelTimeout {
 	head := b.head
 	if head == nil {
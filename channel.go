@@ -0,0 +1,225 @@
+package wheeltimer
+
+import (
+	"sync"
+	"time"
+)
+
+// After waits for the duration to elapse and then sends the current time on the returned channel, like time.After.
+// If the timeout cannot be scheduled, because the timer has been stopped or has too many pending timeouts, After
+// falls back to the Clock of the timer so that the caller is never blocked forever.
+func (tw *WheelTimer) After(d time.Duration) <-chan time.Time {
+	t, err := tw.NewTimer(d)
+	if err != nil {
+		return tw.clock.After(d)
+	}
+	return t.C
+}
+
+// chanTimeout sends the fire times of its current timeout to a channel. Every Stop and Reset starts a new
+// generation, so that a task of an earlier generation which is still running never delivers a stale value.
+type chanTimeout struct {
+	wheel   *WheelTimer
+	c       chan time.Time
+	lock    sync.Mutex
+	gen     uint64
+	when    time.Time // the time at which a ChanTimer is due, on the Clock of the timer
+	timeout Timeout
+	// stopClock stops the Clock which delivers the values of the current generation when its timeout could not be
+	// scheduled, it reports whether a value was still due.
+	stopClock func() bool
+}
+
+func (ct *chanTimeout) task(gen uint64) TimerTask {
+	return TimerTaskFunc(func(timeout Timeout) error {
+		ct.lock.Lock()
+		defer ct.lock.Unlock()
+		if ct.gen != gen {
+			return nil
+		}
+		now := ct.wheel.clock.Now()
+		if now.Before(ct.when) {
+			// a Reset has come too late to move the timeout, which has fired at its previous deadline.
+			ct.startLocked(ct.when.Sub(now), false)
+			return nil
+		}
+		ct.sendLocked(now)
+		return nil
+	})
+}
+
+// sendLocked sends now on the channel. Like time.Ticker, the value is dropped if the receiver is not keeping up.
+func (ct *chanTimeout) sendLocked(now time.Time) {
+	select {
+	case ct.c <- now:
+	default:
+	}
+}
+
+// startLocked schedules the timeout of the current generation, which fires after d, and then every d if periodic.
+// If the timeout cannot be scheduled, because the timer has been stopped or has too many pending timeouts, the
+// values are delivered through the Clock of the timer, like After does, so that the receiver is never blocked
+// forever.
+func (ct *chanTimeout) startLocked(d time.Duration, periodic bool) {
+	var timeout Timeout
+	var err error
+	if periodic {
+		timeout, err = ct.wheel.NewPeriodicTimeout(ct.task(ct.gen), d, d)
+	} else {
+		timeout, err = ct.wheel.NewTimeout(ct.task(ct.gen), d)
+	}
+	if err != nil {
+		ct.stopClock = ct.runOnClock(ct.gen, d, periodic)
+		return
+	}
+	ct.timeout = timeout
+}
+
+// runOnClock delivers the values of generation gen through the Clock of the timer, after d, and then every d if
+// periodic. The returned function stops it, it must be called with the lock held.
+func (ct *chanTimeout) runOnClock(gen uint64, d time.Duration, periodic bool) func() bool {
+	stopCh := make(chan struct{})
+	sent := false // guarded by lock
+	go func() {
+		for {
+			select {
+			case now := <-ct.wheel.clock.After(d):
+				ct.lock.Lock()
+				if ct.gen == gen {
+					ct.sendLocked(now)
+					sent = true
+				}
+				ct.lock.Unlock()
+				if !periodic {
+					return
+				}
+			case <-stopCh:
+				return
+			}
+		}
+	}()
+	return func() bool {
+		close(stopCh)
+		return periodic || !sent
+	}
+}
+
+// stopLocked cancels the current timeout and drains the channel, it reports whether the timeout was pending.
+func (ct *chanTimeout) stopLocked() bool {
+	ct.gen++
+	active := false
+	if ct.timeout != nil {
+		active = ct.timeout.Cancel()
+		ct.timeout = nil
+	}
+	if ct.stopClock != nil {
+		active = ct.stopClock()
+		ct.stopClock = nil
+	}
+	select {
+	case <-ct.c:
+	default:
+	}
+	return active
+}
+
+// ChanTimer is a time.Timer backed by a WheelTimer. It delivers a single value on C when it expires.
+type ChanTimer struct {
+	C  <-chan time.Time
+	ct chanTimeout
+}
+
+// NewTimer creates a ChanTimer which sends the current time on its channel after at least duration d,
+// like time.NewTimer.
+func (tw *WheelTimer) NewTimer(d time.Duration) (*ChanTimer, error) {
+	c := make(chan time.Time, 1)
+	t := &ChanTimer{
+		C: c,
+		ct: chanTimeout{
+			wheel: tw,
+			c:     c,
+			when:  tw.clock.Now().Add(d),
+		},
+	}
+	timeout, err := tw.NewTimeout(t.ct.task(0), d)
+	if err != nil {
+		return nil, err
+	}
+	t.ct.timeout = timeout
+	return t, nil
+}
+
+// Stop prevents the ChanTimer from firing. It returns true if the call stops the timer, false if the timer has
+// already expired or been stopped. As with time.Timer since Go 1.23, no stale value is received from C after
+// Stop returns.
+func (t *ChanTimer) Stop() bool {
+	t.ct.lock.Lock()
+	defer t.ct.lock.Unlock()
+	return t.ct.stopLocked()
+}
+
+// Reset changes the ChanTimer to expire after duration d. It returns true if the timer had been active, false if
+// the timer had expired or been stopped. No stale value is received from C after Reset returns.
+//
+// A pending timeout is moved in place, a new one is only scheduled once the timer has expired or been stopped. If
+// it cannot be scheduled, because the WheelTimer has been stopped or has too many pending timeouts, the value is
+// delivered through the Clock of the WheelTimer instead, like After does.
+func (t *ChanTimer) Reset(d time.Duration) bool {
+	t.ct.lock.Lock()
+	defer t.ct.lock.Unlock()
+
+	t.ct.when = t.ct.wheel.clock.Now().Add(d)
+	if t.ct.timeout != nil && t.ct.timeout.Reset(d) {
+		return true
+	}
+	active := t.ct.stopLocked()
+	t.ct.startLocked(d, false)
+	return active
+}
+
+// Ticker is a time.Ticker backed by a WheelTimer. It delivers the ticks of a fixed-rate periodic timeout on C,
+// and drops them if the receiver is not keeping up.
+type Ticker struct {
+	C  <-chan time.Time
+	ct chanTimeout
+}
+
+// NewTicker creates a Ticker which sends the current time on its channel every duration d, like time.NewTicker.
+func (tw *WheelTimer) NewTicker(d time.Duration) (*Ticker, error) {
+	c := make(chan time.Time, 1)
+	t := &Ticker{
+		C: c,
+		ct: chanTimeout{
+			wheel: tw,
+			c:     c,
+		},
+	}
+	timeout, err := tw.NewPeriodicTimeout(t.ct.task(0), d, d)
+	if err != nil {
+		return nil, err
+	}
+	t.ct.timeout = timeout
+	return t, nil
+}
+
+// Stop turns off the Ticker. No more ticks are received from C after Stop returns.
+func (t *Ticker) Stop() {
+	t.ct.lock.Lock()
+	defer t.ct.lock.Unlock()
+	t.ct.stopLocked()
+}
+
+// Reset stops the Ticker and resets its period to duration d. The next tick arrives after d has elapsed.
+// If the periodic timeout cannot be scheduled, because the WheelTimer has been stopped or has too many pending
+// timeouts, the ticks are delivered through the Clock of the WheelTimer instead, like After does. A period which is
+// not positive leaves the Ticker stopped.
+func (t *Ticker) Reset(d time.Duration) {
+	t.ct.lock.Lock()
+	defer t.ct.lock.Unlock()
+
+	t.ct.stopLocked()
+	if d <= 0 {
+		return
+	}
+	t.ct.startLocked(d, true)
+}
@@ -0,0 +1,127 @@
+package wheeltimer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAfter(t *testing.T) {
+	wheel, advance := newFakeClockWheel(t, time.Millisecond*10)
+	defer wheel.Stop()
+
+	ch := wheel.After(time.Millisecond * 20)
+	advance(time.Millisecond * 10)
+	assert.Len(t, ch, 0)
+	advance(time.Millisecond * 20)
+	assert.Len(t, ch, 1)
+}
+
+func TestChanTimer(t *testing.T) {
+	wheel, advance := newFakeClockWheel(t, time.Millisecond*10)
+	defer wheel.Stop()
+
+	timer, err := wheel.NewTimer(time.Millisecond * 20)
+	assert.NoError(t, err)
+	advance(time.Millisecond * 30)
+	assert.Len(t, timer.C, 1)
+	assert.False(t, timer.Stop())
+	// Stop drains the value which has not been received.
+	assert.Len(t, timer.C, 0)
+
+	assert.False(t, timer.Reset(time.Millisecond*20))
+	assert.True(t, timer.Reset(time.Millisecond*40))
+	advance(time.Millisecond * 30)
+	assert.Len(t, timer.C, 0)
+	advance(time.Millisecond * 20)
+	assert.Len(t, timer.C, 1)
+	<-timer.C
+
+	assert.False(t, timer.Reset(time.Millisecond*10))
+	assert.True(t, timer.Stop())
+	advance(time.Millisecond * 30)
+	assert.Len(t, timer.C, 0)
+}
+
+func TestTicker(t *testing.T) {
+	wheel, advance := newFakeClockWheel(t, time.Millisecond*10)
+	defer wheel.Stop()
+
+	ticker, err := wheel.NewTicker(time.Millisecond * 20)
+	assert.NoError(t, err)
+	advance(time.Millisecond * 30)
+	assert.Len(t, ticker.C, 1)
+	<-ticker.C
+	advance(time.Millisecond * 20)
+	assert.Len(t, ticker.C, 1)
+	// the ticks are dropped while the receiver is not keeping up.
+	advance(time.Millisecond * 40)
+	assert.Len(t, ticker.C, 1)
+
+	ticker.Reset(time.Millisecond * 50)
+	assert.Len(t, ticker.C, 0)
+	advance(time.Millisecond * 40)
+	assert.Len(t, ticker.C, 0)
+	advance(time.Millisecond * 20)
+	assert.Len(t, ticker.C, 1)
+
+	ticker.Stop()
+	assert.Len(t, ticker.C, 0)
+	advance(time.Millisecond * 100)
+	assert.Len(t, ticker.C, 0)
+
+	_, err = wheel.NewTicker(0)
+	assert.Error(t, err)
+}
+
+func TestChanTimer_ResetInPlace(t *testing.T) {
+	wheel, advance := newFakeClockWheel(t, time.Millisecond*10)
+	defer wheel.Stop()
+
+	timer, err := wheel.NewTimer(time.Millisecond * 20)
+	assert.NoError(t, err)
+	timeout := timer.ct.timeout
+	assert.True(t, timer.Reset(time.Millisecond*40))
+	assert.Same(t, timeout, timer.ct.timeout)
+	advance(time.Millisecond * 30)
+	assert.Len(t, timer.C, 0)
+	advance(time.Millisecond * 20)
+	assert.Len(t, timer.C, 1)
+}
+
+func TestChanTimer_ResetFallback(t *testing.T) {
+	wheel, err := NewWheelTimer(time.Millisecond*10, 64, WithMaxPendingTimeouts(1))
+	assert.NoError(t, err)
+	defer wheel.Stop()
+
+	timer, err := wheel.NewTimer(time.Millisecond * 10)
+	assert.NoError(t, err)
+	<-timer.C
+	ticker, err := wheel.NewTicker(time.Millisecond * 10)
+	assert.NoError(t, err)
+
+	// the ticker takes the only pending timeout, so the timer falls back to the Clock.
+	assert.False(t, timer.Reset(time.Millisecond*10))
+	select {
+	case <-timer.C:
+	case <-time.After(time.Second):
+		assert.Fail(t, "the timer did not fire")
+	}
+
+	assert.False(t, timer.Reset(time.Millisecond*10))
+	assert.True(t, timer.Stop())
+	time.Sleep(time.Millisecond * 30)
+	assert.Len(t, timer.C, 0)
+
+	// the timeout of the ticker is released asynchronously, so the ticker may fall back as well.
+	ticker.Reset(time.Millisecond * 10)
+	for i := 0; i < 2; i++ {
+		select {
+		case <-ticker.C:
+		case <-time.After(time.Second):
+			assert.Fail(t, "the ticker did not tick")
+		}
+	}
+	ticker.Stop()
+}
@@ -0,0 +1,178 @@
+package wheeltimer
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock is the source of time of a WheelTimer.
+type Clock interface {
+	// Now is Returns the current time.
+	Now() time.Time
+
+	// Since is Returns the time elapsed since t.
+	Since(t time.Time) time.Duration
+
+	// After is Returns a channel which receives the current time once the duration d has elapsed.
+	After(d time.Duration) <-chan time.Time
+
+	// AfterFunc is Calls f on its own goroutine once the duration d has elapsed. The returned function stops the
+	// call, and reports whether it has stopped it before f has been called.
+	AfterFunc(d time.Duration, f func()) (stop func() bool)
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) Since(t time.Time) time.Duration {
+	return time.Since(t)
+}
+
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+func (realClock) AfterFunc(d time.Duration, f func()) func() bool {
+	return time.AfterFunc(d, f).Stop
+}
+
+// NewRealClock creates a Clock backed by the time package.
+func NewRealClock() Clock {
+	return realClock{}
+}
+
+type waiter struct {
+	until time.Time
+	ch    chan time.Time
+	f     func()
+}
+
+// FakeClock is a Clock which only moves when Advance is called, so that a WheelTimer can be driven
+// deterministically in tests.
+type FakeClock struct {
+	lock     sync.Mutex
+	now      time.Time
+	waiters  []*waiter
+	funcs    []*waiter     // the calls of AfterFunc, which are not waited for by BlockUntil
+	changeCh chan struct{} // closed and replaced whenever the waiters change
+}
+
+// NewFakeClock creates a FakeClock whose current time is now.
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{
+		now:      now,
+		changeCh: make(chan struct{}),
+	}
+}
+
+func (c *FakeClock) Now() time.Time {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.now
+}
+
+func (c *FakeClock) Since(t time.Time) time.Duration {
+	return c.Now().Sub(t)
+}
+
+func (c *FakeClock) After(d time.Duration) <-chan time.Time {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	w := &waiter{
+		until: c.now.Add(d),
+		ch:    make(chan time.Time, 1),
+	}
+	if d <= 0 {
+		w.ch <- c.now
+		return w.ch
+	}
+	c.waiters = append(c.waiters, w)
+	c.notifyLocked()
+	return w.ch
+}
+
+func (c *FakeClock) AfterFunc(d time.Duration, f func()) func() bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if d <= 0 {
+		go f()
+		return func() bool {
+			return false
+		}
+	}
+	w := &waiter{
+		until: c.now.Add(d),
+		f:     f,
+	}
+	c.funcs = append(c.funcs, w)
+	return func() bool {
+		c.lock.Lock()
+		defer c.lock.Unlock()
+		for i, pending := range c.funcs {
+			if pending == w {
+				c.funcs = append(c.funcs[:i], c.funcs[i+1:]...)
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Advance moves the clock forward by d, fires the channels returned by After and starts the calls of AfterFunc
+// whose duration has elapsed.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.now = c.now.Add(d)
+	funcs := c.funcs[:0]
+	for _, w := range c.funcs {
+		if c.now.Before(w.until) {
+			funcs = append(funcs, w)
+		} else {
+			go w.f()
+		}
+	}
+	for i := len(funcs); i < len(c.funcs); i++ {
+		c.funcs[i] = nil
+	}
+	c.funcs = funcs
+	waiters := c.waiters[:0]
+	for _, w := range c.waiters {
+		if c.now.Before(w.until) {
+			waiters = append(waiters, w)
+		} else {
+			w.ch <- c.now
+		}
+	}
+	for i := len(waiters); i < len(c.waiters); i++ {
+		c.waiters[i] = nil
+	}
+	c.waiters = waiters
+	c.notifyLocked()
+}
+
+// BlockUntil blocks until at least n channels returned by After are waiting to be fired. For a started
+// WheelTimer, BlockUntil(1) returns once the worker has processed the current tick and waits for the next one.
+func (c *FakeClock) BlockUntil(n int) {
+	for {
+		c.lock.Lock()
+		if len(c.waiters) >= n {
+			c.lock.Unlock()
+			return
+		}
+		ch := c.changeCh
+		c.lock.Unlock()
+		<-ch
+	}
+}
+
+func (c *FakeClock) notifyLocked() {
+	close(c.changeCh)
+	c.changeCh = make(chan struct{})
+}
@@ -0,0 +1,56 @@
+package wheeltimer
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFakeClock(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := NewFakeClock(start)
+	assert.Equal(t, start, clock.Now())
+
+	ch := clock.After(time.Second)
+	clock.BlockUntil(1)
+	clock.Advance(time.Millisecond * 999)
+	assert.Len(t, ch, 0)
+	assert.Equal(t, time.Millisecond*999, clock.Since(start))
+
+	clock.Advance(time.Millisecond)
+	assert.Equal(t, start.Add(time.Second), <-ch)
+
+	// a non-positive duration fires immediately.
+	assert.Equal(t, start.Add(time.Second), <-clock.After(0))
+
+	var woken atomic.Bool
+	go func() {
+		<-clock.After(time.Second)
+		woken.Store(true)
+	}()
+	clock.BlockUntil(1)
+	clock.Advance(time.Second)
+	assert.Eventually(t, woken.Load, time.Second, time.Millisecond)
+}
+
+func TestFakeClock_AfterFunc(t *testing.T) {
+	clock := NewFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	fired := make(chan struct{})
+	clock.AfterFunc(time.Second, func() {
+		close(fired)
+	})
+	var stopped atomic.Bool
+	stop := clock.AfterFunc(time.Second, func() {
+		stopped.Store(true)
+	})
+	clock.Advance(time.Millisecond * 999)
+	assert.True(t, stop())
+	assert.False(t, stop())
+
+	clock.Advance(time.Millisecond)
+	<-fired
+	assert.False(t, stopped.Load())
+}
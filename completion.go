@@ -0,0 +1,126 @@
+package wheeltimer
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// closedCh is a reusable closed channel, for the timeouts which have completed before Done has been called.
+var closedCh = make(chan struct{})
+
+func init() {
+	close(closedCh)
+}
+
+// completion signals the outcome of a timeout, in the same way as the Done channel and Err of a context.
+type completion struct {
+	lock     sync.Mutex
+	done     atomic.Value // of chan struct{}, created lazily
+	finished bool
+	err      error
+	hooks    []func()
+}
+
+func (c *completion) Done() <-chan struct{} {
+	if done := c.done.Load(); done != nil {
+		return done.(chan struct{})
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	done := c.done.Load()
+	if done == nil {
+		done = make(chan struct{})
+		c.done.Store(done)
+	}
+	return done.(chan struct{})
+}
+
+func (c *completion) Err() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.err
+}
+
+// complete records the outcome, closes the Done channel and calls the hooks. It reports whether this call has
+// completed it.
+func (c *completion) complete(err error) bool {
+	c.lock.Lock()
+	if c.finished {
+		c.lock.Unlock()
+		return false
+	}
+	c.finished = true
+	c.err = err
+	if done, _ := c.done.Load().(chan struct{}); done != nil {
+		close(done)
+	} else {
+		c.done.Store(closedCh)
+	}
+	hooks := c.hooks
+	c.hooks = nil
+	c.lock.Unlock()
+
+	for _, f := range hooks {
+		f()
+	}
+	return true
+}
+
+// afterDone arranges for f to be called once the completion is done, by the goroutine which completes it, or
+// right away if it is done already. Like context.AfterFunc, f must not block.
+func (c *completion) afterDone(f func()) {
+	c.lock.Lock()
+	if !c.finished {
+		c.hooks = append(c.hooks, f)
+		c.lock.Unlock()
+		return
+	}
+	c.lock.Unlock()
+	f()
+}
+
+// Future is the handle of a scheduled function which produces a value.
+type Future[T any] struct {
+	Timeout
+	value T
+}
+
+// NewTimeoutFunc schedules f for one-time execution after the specified delay on timer, and returns a Future
+// which holds its result.
+func NewTimeoutFunc[T any](timer *WheelTimer, f func(Timeout) (T, error), delay time.Duration, opts ...TimeoutOption) (*Future[T], error) {
+	future := &Future[T]{}
+	timeout, err := timer.NewTimeoutWithOptions(TimerTaskFunc(func(timeout Timeout) error {
+		value, err := f(timeout)
+		future.value = value
+		return err
+	}), delay, opts...)
+	if err != nil {
+		return nil, err
+	}
+	future.Timeout = timeout
+	return future, nil
+}
+
+func (f *Future[T]) unwrap() Timeout {
+	return f.Timeout
+}
+
+// Get waits for the function to complete and returns its result. If the timeout has been cancelled, the error is
+// ErrCancelled; if ctx is done first, the error is the one of ctx.
+func (f *Future[T]) Get(ctx context.Context) (T, error) {
+	select {
+	case <-f.Done():
+	case <-ctx.Done():
+		var zero T
+		return zero, ctx.Err()
+	}
+
+	if err := f.Err(); err != nil {
+		var zero T
+		return zero, err
+	}
+	return f.value, nil
+}
@@ -0,0 +1,85 @@
+package wheeltimer
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimeoutDone(t *testing.T) {
+	wheel, advance := newFakeClockWheel(t, time.Millisecond*10, WithPanicHandler(func(interface{}) {}))
+	defer wheel.Stop()
+
+	errTask := errors.New("task failed")
+	newTimeout := func(f func(Timeout) error) Timeout {
+		timeout, err := wheel.NewTimeout(TimerTaskFunc(f), time.Millisecond*10)
+		assert.NoError(t, err)
+		return timeout
+	}
+	succeeded := newTimeout(func(Timeout) error { return nil })
+	failed := newTimeout(func(Timeout) error { return errTask })
+	panicked := newTimeout(func(Timeout) error { panic("boom") })
+	cancelled := newTimeout(func(Timeout) error { return nil })
+
+	for _, timeout := range []Timeout{succeeded, failed, panicked, cancelled} {
+		assert.Len(t, timeout.Done(), 0)
+		assert.NoError(t, timeout.Err())
+	}
+
+	assert.True(t, cancelled.Cancel())
+	<-cancelled.Done()
+	assert.ErrorIs(t, cancelled.Err(), ErrCancelled)
+
+	advance(time.Millisecond * 20)
+	<-succeeded.Done()
+	assert.NoError(t, succeeded.Err())
+	<-failed.Done()
+	assert.ErrorIs(t, failed.Err(), errTask)
+	<-panicked.Done()
+	var panicErr *PanicError
+	assert.ErrorAs(t, panicked.Err(), &panicErr)
+	assert.Equal(t, "boom", panicErr.Value)
+	assert.NotEmpty(t, panicErr.Stack)
+}
+
+func TestPeriodicTimeoutDone(t *testing.T) {
+	wheel, advance := newFakeClockWheel(t, time.Millisecond*10)
+	defer wheel.Stop()
+
+	timeout, err := wheel.NewPeriodicTimeout(TimerTaskFunc(func(Timeout) error { return nil }), 0, time.Millisecond*10)
+	assert.NoError(t, err)
+	advance(time.Millisecond * 30)
+	assert.Len(t, timeout.Done(), 0)
+
+	assert.True(t, timeout.Cancel())
+	<-timeout.Done()
+	assert.ErrorIs(t, timeout.Err(), ErrCancelled)
+}
+
+func TestNewTimeoutFunc(t *testing.T) {
+	wheel, advance := newFakeClockWheel(t, time.Millisecond*10)
+	defer wheel.Stop()
+
+	future, err := NewTimeoutFunc(wheel, func(Timeout) (int, error) { return 42, nil }, time.Millisecond*10)
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = future.Get(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	advance(time.Millisecond * 20)
+	value, err := future.Get(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 42, value)
+
+	cancelled, err := NewTimeoutFunc(wheel, func(Timeout) (string, error) { return "never", nil }, time.Millisecond*10)
+	assert.NoError(t, err)
+	assert.True(t, cancelled.Cancel())
+	value2, err := cancelled.Get(context.Background())
+	assert.ErrorIs(t, err, ErrCancelled)
+	assert.Empty(t, value2)
+}
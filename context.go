@@ -0,0 +1,77 @@
+package wheeltimer
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+type timeoutContext struct {
+	ctx context.Context
+	// stop unregisters the cancellation of the timeout from ctx, it is set once the timeout has been scheduled.
+	stop atomic.Pointer[func() bool]
+}
+
+// NewTimeoutContext schedules the specified TimerTask for one-time execution after the specified delay, and cancels
+// the timeout when ctx is done before it expires. If task is a ContextTimerTask, the context it runs with is derived
+// from ctx, so the task is also cancelled when ctx is done while it is running.
+func (tw *WheelTimer) NewTimeoutContext(ctx context.Context, task TimerTask, delay time.Duration, opts ...TimeoutOption) (Timeout, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	timeout := newWheelTimeout(tw, task, 0)
+	timeout.apply(opts)
+	timeout.ctx = &timeoutContext{ctx: ctx}
+	if err := tw.schedule(timeout, delay); err != nil {
+		return nil, err
+	}
+
+	stop := context.AfterFunc(ctx, func() {
+		timeout.Cancel()
+	})
+	timeout.ctx.stop.Store(&stop)
+	// the timeout may have expired or been cancelled before stop was set.
+	if timeout.State() != TimeoutStateScheduled {
+		stop()
+	}
+	return timeout, nil
+}
+
+// releaseContext unregisters the timeout from its context once it has left the scheduled state, so that nothing is
+// leaked until the context is done.
+func (timeout *WheelTimeout) releaseContext() {
+	if timeout.ctx == nil {
+		return
+	}
+	if stop := timeout.ctx.stop.Load(); stop != nil {
+		(*stop)()
+	}
+}
+
+func (timeout *WheelTimeout) runTask(task TimerTask) error {
+	contextTask, ok := task.(ContextTimerTask)
+	if !ok && timeout.timeLimit <= 0 {
+		return task.Run(timeout)
+	}
+
+	parent := context.Background()
+	if timeout.ctx != nil {
+		parent = timeout.ctx.ctx
+	}
+	ctx, cancel := context.WithCancel(parent)
+	defer cancel()
+	stop := context.AfterFunc(timeout.timer.ctx, cancel)
+	defer stop()
+
+	if timeout.timeLimit > 0 {
+		var stopLimit func()
+		ctx, stopLimit = timeout.limitTime(ctx)
+		defer stopLimit()
+	}
+
+	if !ok {
+		return task.Run(timeout)
+	}
+	return contextTask.RunContext(ctx, timeout)
+}
@@ -0,0 +1,78 @@
+package wheeltimer
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewTimeoutContext(t *testing.T) {
+	wheel, err := NewWheelTimer(time.Millisecond*10, 64)
+	assert.NoError(t, err)
+	defer wheel.Stop()
+
+	t.Run("CancelledBeforeExpiry", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		var fired atomic.Bool
+		timeout, err := wheel.NewTimeoutContext(ctx, TimerTaskFunc(func(timeout Timeout) error {
+			fired.Store(true)
+			return nil
+		}), time.Millisecond*30)
+		assert.NoError(t, err)
+
+		cancel()
+		assert.Eventually(t, timeout.IsCancelled, time.Second, time.Millisecond)
+		time.Sleep(time.Millisecond * 50)
+		assert.False(t, fired.Load())
+		assert.Equal(t, int64(0), wheel.PendingTimeouts())
+	})
+
+	t.Run("AlreadyDone", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := wheel.NewTimeoutContext(ctx, TimerTaskFunc(func(timeout Timeout) error { return nil }), 0)
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+
+	t.Run("CancelledWhileRunning", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		started := make(chan struct{})
+		taskErr := make(chan error, 1)
+		timeout, err := wheel.NewTimeoutContext(ctx, ContextTimerTaskFunc(func(ctx context.Context, timeout Timeout) error {
+			close(started)
+			<-ctx.Done()
+			taskErr <- ctx.Err()
+			return nil
+		}), time.Millisecond*10)
+		assert.NoError(t, err)
+
+		<-started
+		assert.True(t, timeout.IsExpired())
+		cancel()
+		assert.ErrorIs(t, <-taskErr, context.Canceled)
+		assert.False(t, timeout.IsCancelled())
+	})
+}
+
+func TestContextTimerTask_Stop(t *testing.T) {
+	wheel, err := NewWheelTimer(time.Millisecond*10, 64)
+	assert.NoError(t, err)
+
+	started := make(chan struct{})
+	var taskErr atomic.Value
+	_, err = wheel.NewTimeout(ContextTimerTaskFunc(func(ctx context.Context, timeout Timeout) error {
+		close(started)
+		<-ctx.Done()
+		taskErr.Store(ctx.Err())
+		return nil
+	}), time.Millisecond*10)
+	assert.NoError(t, err)
+
+	<-started
+	_, err = wheel.StopAndWait(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, context.Canceled, taskErr.Load())
+}
@@ -0,0 +1,67 @@
+package wheeltimer
+
+import (
+	"fmt"
+
+	"github.com/adol1111/wheeltimer/cron"
+)
+
+// NewCronTimeout schedules the specified TimerTask to run at the times described by the cron expression spec.
+// See cron.Parse for the accepted syntax, time zones and the handling of daylight saving time.
+func (tw *WheelTimer) NewCronTimeout(spec string, task TimerTask, opts ...TimeoutOption) (PeriodicTimeout, error) {
+	schedule, err := cron.Parse(spec)
+	if err != nil {
+		return nil, err
+	}
+	return tw.NewScheduleTimeout(schedule, task, opts...)
+}
+
+// NewScheduleTimeout schedules the specified TimerTask to run at the fire times of schedule.
+// As with NewPeriodicTimeout, the runs of a slow task may overlap, and fire times missed because the
+// worker fell behind are skipped. The returned Timeout expires once schedule has no fire time left.
+func (tw *WheelTimer) NewScheduleTimeout(schedule cron.Schedule, task TimerTask, opts ...TimeoutOption) (PeriodicTimeout, error) {
+	now := tw.clock.Now()
+	next := schedule.Next(now)
+	if next.IsZero() {
+		return nil, fmt.Errorf("schedule has no fire time after %s", now)
+	}
+
+	timeout := newWheelTimeout(tw, task, 0)
+	timeout.apply(opts)
+	timeout.periodic = &periodic{
+		schedule: schedule,
+		next:     next,
+	}
+	timeout.wallDeadline = next.UnixNano()
+	if err := tw.schedule(timeout, next.Sub(now)); err != nil {
+		return nil, err
+	}
+	return timeout, nil
+}
+
+// expireSchedule hands a timeout created by NewScheduleTimeout to the executor and puts it back into the wheel
+// at the next fire time of its schedule.
+func (timeout *WheelTimeout) expireSchedule() {
+	tw := timeout.timer
+	p := timeout.periodic
+	timeout.execute(timeout.run)
+	if tw.State() == workerStateDraining {
+		timeout.finishPeriodic(true)
+		return
+	}
+
+	now := tw.clock.Now()
+	next := p.schedule.Next(p.next)
+	if !next.IsZero() && !next.After(now) {
+		next = p.schedule.Next(now)
+	}
+	if next.IsZero() {
+		timeout.finishPeriodic(true)
+		return
+	}
+
+	p.next = next
+	timeout.wallDeadline = next.UnixNano()
+	timeout.setDeadline(tw.elapsed() + next.Sub(now))
+	tw.reschedule(timeout)
+}
@@ -0,0 +1,162 @@
+// Package cron parses cron expressions and computes their fire times.
+package cron
+
+import (
+	"time"
+)
+
+// Schedule describes the fire times of a job.
+type Schedule interface {
+	// Next is Returns the first fire time strictly after t, or the zero time if there is none.
+	Next(t time.Time) time.Time
+}
+
+// EverySchedule fires at a constant interval, as created by "@every <duration>".
+type EverySchedule struct {
+	Delay time.Duration
+}
+
+func (s EverySchedule) Next(t time.Time) time.Time {
+	return t.Add(s.Delay)
+}
+
+// SpecSchedule is a schedule given by cron fields. Each field is a bit set of the values at which it matches.
+//
+// Daylight saving time is handled on the wall clock of Location:
+//   - a fire time that falls into a gap created by a spring-forward transition does not exist and is skipped;
+//   - a fire time that falls into the hour repeated by a fall-back transition fires only once, unless the hour
+//     field is "*", in which case the job keeps firing on elapsed time through the repeated hour.
+type SpecSchedule struct {
+	Second, Minute, Hour, Dom, Month, Dow uint64
+	Location                              *time.Location
+}
+
+// searchYears bounds the search for the next fire time, so that impossible dates like "30 2 30 2 *" terminate.
+const searchYears = 5
+
+func (s *SpecSchedule) Next(t time.Time) time.Time {
+	origLocation := t.Location()
+	loc := s.Location
+	if loc == nil {
+		loc = time.Local
+	}
+	t = t.In(loc)
+
+	// start at the next whole second.
+	t = t.Add(time.Second - time.Duration(t.Nanosecond()))
+
+	next := s.next(t, loc)
+	if !next.IsZero() && s.Hour&starBit == 0 && repeatedWallClock(next) {
+		next = s.next(next.Add(time.Second), loc)
+	}
+	if next.IsZero() {
+		return next
+	}
+	return next.In(origLocation)
+}
+
+func (s *SpecSchedule) next(t time.Time, loc *time.Location) time.Time {
+	// truncated records whether t has been moved forward, in which case the smaller fields
+	// start again from their minimum.
+	truncated := false
+	yearLimit := t.Year() + searchYears
+
+WRAP:
+	if t.Year() > yearLimit {
+		return time.Time{}
+	}
+
+	for 1<<uint(t.Month())&s.Month == 0 {
+		if !truncated {
+			truncated = true
+			t = time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, loc)
+		}
+		t = t.AddDate(0, 1, 0)
+		if t.Month() == time.January {
+			goto WRAP
+		}
+	}
+
+	for !s.dayMatches(t) {
+		if !truncated {
+			truncated = true
+			t = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+		}
+		t = t.AddDate(0, 0, 1)
+		// midnight may not exist, or exist twice, on a DST transition day; move back to the start of the day.
+		if t.Hour() != 0 {
+			if t.Hour() > 12 {
+				t = t.Add(time.Duration(24-t.Hour()) * time.Hour)
+			} else {
+				t = t.Add(-time.Duration(t.Hour()) * time.Hour)
+			}
+		}
+		if t.Day() == 1 {
+			goto WRAP
+		}
+	}
+
+	for 1<<uint(t.Hour())&s.Hour == 0 {
+		if !truncated {
+			truncated = true
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, loc)
+		}
+		t = t.Add(time.Hour)
+		if t.Hour() == 0 {
+			goto WRAP
+		}
+	}
+
+	for 1<<uint(t.Minute())&s.Minute == 0 {
+		if !truncated {
+			truncated = true
+			t = t.Truncate(time.Minute)
+		}
+		t = t.Add(time.Minute)
+		if t.Minute() == 0 {
+			goto WRAP
+		}
+	}
+
+	for 1<<uint(t.Second())&s.Second == 0 {
+		if !truncated {
+			truncated = true
+			t = t.Truncate(time.Second)
+		}
+		t = t.Add(time.Second)
+		if t.Second() == 0 {
+			goto WRAP
+		}
+	}
+
+	return t
+}
+
+// dayMatches implements the cron rule that a restricted day-of-month and a restricted day-of-week
+// match if either of them matches, while a "*" in one of them leaves only the other one in effect.
+func (s *SpecSchedule) dayMatches(t time.Time) bool {
+	domMatch := 1<<uint(t.Day())&s.Dom > 0
+	dowMatch := 1<<uint(t.Weekday())&s.Dow > 0
+	if s.Dom&starBit > 0 || s.Dow&starBit > 0 {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
+
+// repeatedWallClock reports whether the wall clock reading of t already occurred earlier,
+// because a fall-back transition happened shortly before t.
+func repeatedWallClock(t time.Time) bool {
+	_, offset := t.Zone()
+	// no zone moves its clocks back by more than a couple of hours.
+	_, before := t.Add(-3 * time.Hour).Zone()
+	if before <= offset {
+		return false
+	}
+
+	earlier := t.Add(-time.Duration(before-offset) * time.Second)
+	y1, m1, d1 := earlier.Date()
+	y2, m2, d2 := t.Date()
+	h1, min1, s1 := earlier.Clock()
+	h2, min2, s2 := t.Clock()
+	return y1 == y2 && m1 == m2 && d1 == d2 && h1 == h2 && min1 == min2 && s1 == s2
+}
@@ -0,0 +1,93 @@
+package cron
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseErrors(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"* * * *",
+		"* * * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"1-2-3 * * * *",
+		"* * * foo *",
+		"@every",
+		"@every -1s",
+		"@fortnightly",
+		"CRON_TZ=Nowhere/Nothing * * * * *",
+		"TZ=UTC",
+	} {
+		_, err := Parse(spec)
+		assert.Error(t, err, spec)
+	}
+}
+
+func TestNext(t *testing.T) {
+	var tests = []struct {
+		spec     string
+		from     string
+		expected string
+	}{
+		{"* * * * *", "2024-01-01T10:00:30Z", "2024-01-01T10:01:00Z"},
+		{"*/15 * * * * *", "2024-01-01T10:00:14Z", "2024-01-01T10:00:15Z"},
+		{"30 9 * * mon-fri", "2024-01-05T09:30:00Z", "2024-01-08T09:30:00Z"},
+		{"0 0 29 2 *", "2024-03-01T00:00:00Z", "2028-02-29T00:00:00Z"},
+		{"0 12 1 * sun", "2024-01-01T13:00:00Z", "2024-01-07T12:00:00Z"},
+		{"0 12 * * 7", "2024-01-01T13:00:00Z", "2024-01-07T12:00:00Z"},
+		{"0 0 1,15 jan,jul ?", "2024-01-02T00:00:00Z", "2024-01-15T00:00:00Z"},
+		{"5/20 * * * *", "2024-01-01T10:06:00Z", "2024-01-01T10:25:00Z"},
+		{"@hourly", "2024-01-01T10:00:00Z", "2024-01-01T11:00:00Z"},
+		{"@daily", "2024-12-31T10:00:00Z", "2025-01-01T00:00:00Z"},
+		{"@weekly", "2024-01-01T00:00:00Z", "2024-01-07T00:00:00Z"},
+		{"@every 90s", "2024-01-01T10:00:00Z", "2024-01-01T10:01:30Z"},
+		{"CRON_TZ=Asia/Tokyo 0 9 * * *", "2024-01-01T00:00:00Z", "2024-01-02T00:00:00Z"},
+	}
+
+	for _, test := range tests {
+		schedule, err := ParseInLocation(test.spec, time.UTC)
+		assert.NoError(t, err, test.spec)
+		from, _ := time.Parse(time.RFC3339, test.from)
+		expected, _ := time.Parse(time.RFC3339, test.expected)
+		assert.Equal(t, expected, schedule.Next(from).UTC(), test.spec)
+	}
+}
+
+func TestNextImpossible(t *testing.T) {
+	schedule, err := ParseInLocation("0 0 30 2 *", time.UTC)
+	assert.NoError(t, err)
+	assert.True(t, schedule.Next(time.Now()).IsZero())
+}
+
+func TestNextDaylightSaving(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	assert.NoError(t, err)
+
+	// 2024-03-10 02:30 does not exist in New York, the run of that day is skipped.
+	schedule, err := ParseInLocation("30 2 * * *", loc)
+	assert.NoError(t, err)
+	next := schedule.Next(time.Date(2024, 3, 10, 0, 0, 0, 0, loc))
+	assert.Equal(t, time.Date(2024, 3, 11, 2, 30, 0, 0, loc), next)
+
+	// 2024-11-03 01:30 occurs twice in New York, it fires only once.
+	schedule, err = ParseInLocation("30 1 * * *", loc)
+	assert.NoError(t, err)
+	first := schedule.Next(time.Date(2024, 11, 3, 0, 0, 0, 0, loc))
+	assert.Equal(t, "2024-11-03T01:30:00-04:00", first.Format(time.RFC3339))
+	assert.Equal(t, "2024-11-04T01:30:00-05:00", schedule.Next(first).Format(time.RFC3339))
+
+	// an hourly job keeps running through the repeated hour.
+	schedule, err = ParseInLocation("30 * * * *", loc)
+	assert.NoError(t, err)
+	assert.Equal(t, "2024-11-03T01:30:00-05:00", schedule.Next(first).Format(time.RFC3339))
+}
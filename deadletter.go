@@ -0,0 +1,154 @@
+package wheeltimer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// DeadLetter describes a TimerTask which has failed for good: a one-shot task whose last attempt returned an error
+// or panicked, or a run of a periodic task which panicked.
+type DeadLetter struct {
+	// Timeout is the handle of the failed task, Timeout.Task can be scheduled again to re-enqueue it.
+	Timeout Timeout
+	// Err is the error of the last attempt, a *PanicError holding the panic value and stack if the task panicked.
+	Err error
+	// Attempts is the number of runs of a one-shot task, or 0 for a periodic task.
+	Attempts int
+	// Deadline is the deadline the task has originally been scheduled for.
+	Deadline time.Time
+	// FailedAt is the time at which the last attempt returned.
+	FailedAt time.Time
+}
+
+// DeadLetterSink receives the tasks which have failed for good, see WithDeadLetterSink. Put is called on the
+// goroutine which has run the task, before the timeout is completed.
+type DeadLetterSink interface {
+	// Put is Stores the specified DeadLetter.
+	Put(letter DeadLetter) error
+}
+
+// MemoryDeadLetterSink keeps the dead letters in memory, dropping the oldest ones beyond its capacity.
+type MemoryDeadLetterSink struct {
+	lock     sync.Mutex
+	capacity int
+	letters  []DeadLetter
+}
+
+// NewMemoryDeadLetterSink creates a MemoryDeadLetterSink which keeps at most capacity dead letters, or all of them
+// if capacity is not positive.
+func NewMemoryDeadLetterSink(capacity int) *MemoryDeadLetterSink {
+	return &MemoryDeadLetterSink{capacity: capacity}
+}
+
+func (s *MemoryDeadLetterSink) Put(letter DeadLetter) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.capacity > 0 && len(s.letters) >= s.capacity {
+		copy(s.letters, s.letters[1:])
+		s.letters = s.letters[:len(s.letters)-1]
+	}
+	s.letters = append(s.letters, letter)
+	return nil
+}
+
+// Letters is Returns a copy of the dead letters, oldest first.
+func (s *MemoryDeadLetterSink) Letters() []DeadLetter {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([]DeadLetter(nil), s.letters...)
+}
+
+// Drain is Returns the dead letters, oldest first, and removes them from the sink.
+func (s *MemoryDeadLetterSink) Drain() []DeadLetter {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	letters := s.letters
+	s.letters = nil
+	return letters
+}
+
+// FileDeadLetterSink appends the dead letters to a file as JSON lines, see FileDeadLetter for the format.
+type FileDeadLetterSink struct {
+	lock    sync.Mutex
+	file    *os.File
+	encoder *json.Encoder
+}
+
+// FileDeadLetter is a line of the file written by FileDeadLetterSink.
+type FileDeadLetter struct {
+	Task      string    `json:"task"`
+	TimeoutID uint64    `json:"timeout_id"`
+	Key       string    `json:"key,omitempty"`
+	Error     string    `json:"error"`
+	Panic     string    `json:"panic,omitempty"`
+	Stack     string    `json:"stack,omitempty"`
+	Attempts  int       `json:"attempts"`
+	Deadline  time.Time `json:"deadline"`
+	FailedAt  time.Time `json:"failed_at"`
+}
+
+// NewFileDeadLetterSink opens the file at path for appending, creating it if needed.
+func NewFileDeadLetterSink(path string) (*FileDeadLetterSink, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &FileDeadLetterSink{
+		file:    file,
+		encoder: json.NewEncoder(file),
+	}, nil
+}
+
+func (s *FileDeadLetterSink) Put(letter DeadLetter) error {
+	// the timeout itself is not formatted, a periodic timeout is being rescheduled by the worker meanwhile.
+	line := FileDeadLetter{
+		Task:      fmt.Sprintf("%v", letter.Timeout.Task()),
+		TimeoutID: letter.Timeout.ID(),
+		Key:       letter.Timeout.Key(),
+		Attempts:  letter.Attempts,
+		Deadline:  letter.Deadline,
+		FailedAt:  letter.FailedAt,
+	}
+	if letter.Err != nil {
+		line.Error = letter.Err.Error()
+	}
+	var panicErr *PanicError
+	if errors.As(letter.Err, &panicErr) {
+		line.Panic = fmt.Sprintf("%v", panicErr.Value)
+		line.Stack = string(panicErr.Stack)
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.encoder.Encode(line)
+}
+
+// Close closes the file.
+func (s *FileDeadLetterSink) Close() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.file.Close()
+}
+
+// deadLetter hands a task which has failed for good with err to the DeadLetterSink of the timer.
+func (timeout *WheelTimeout) deadLetter(err error) {
+	tw := timeout.timer
+	if tw.deadLetterSink == nil {
+		return
+	}
+
+	letter := DeadLetter{
+		Timeout:  timeout,
+		Err:      err,
+		Attempts: timeout.Attempt(),
+		Deadline: tw.startTime.Load().(time.Time).Add(timeout.firstDeadline),
+		FailedAt: tw.clock.Now(),
+	}
+	if err := tw.deadLetterSink.Put(letter); err != nil {
+		tw.logger.Error("[wheeltimer] dead letter sink error", "error", err)
+	}
+}
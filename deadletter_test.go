@@ -0,0 +1,137 @@
+package wheeltimer
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeadLetterSink(t *testing.T) {
+	sink := NewMemoryDeadLetterSink(0)
+	wheel, advance := newFakeClockWheel(t, time.Millisecond*10,
+		WithDeadLetterSink(sink),
+		WithPanicHandler(func(interface{}) {}),
+		WithRetryPolicy(&RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond * 10}))
+	defer wheel.Stop()
+	start := wheel.startTime.Load().(time.Time)
+
+	errFailed := errors.New("failed")
+	failed, err := wheel.NewTimeout(TimerTaskFunc(func(timeout Timeout) error {
+		return errFailed
+	}), time.Millisecond*20)
+	assert.NoError(t, err)
+	panicked, err := wheel.NewTimeoutWithOptions(TimerTaskFunc(func(timeout Timeout) error {
+		panic("boom")
+	}), time.Millisecond*20, WithTimeoutRetryPolicy(nil))
+	assert.NoError(t, err)
+	_, err = wheel.NewTimeout(TimerTaskFunc(func(timeout Timeout) error {
+		return nil
+	}), time.Millisecond*20)
+	assert.NoError(t, err)
+
+	advance(time.Millisecond * 100)
+	letters := sink.Drain()
+	assert.Len(t, letters, 2)
+	assert.Empty(t, sink.Letters())
+
+	assert.Same(t, panicked, letters[0].Timeout)
+	var panicErr *PanicError
+	assert.ErrorAs(t, letters[0].Err, &panicErr)
+	assert.Equal(t, "boom", panicErr.Value)
+	assert.NotEmpty(t, panicErr.Stack)
+	assert.Equal(t, 1, letters[0].Attempts)
+
+	assert.Same(t, failed, letters[1].Timeout)
+	assert.ErrorIs(t, letters[1].Err, errFailed)
+	assert.Equal(t, 2, letters[1].Attempts)
+	assert.Equal(t, start.Add(time.Millisecond*20), letters[1].Deadline)
+	assert.True(t, letters[1].FailedAt.After(letters[1].Deadline))
+
+	// a dead letter can be re-enqueued.
+	_, err = wheel.NewTimeout(letters[1].Timeout.Task(), 0)
+	assert.NoError(t, err)
+	advance(time.Millisecond * 40)
+	assert.Len(t, sink.Letters(), 1)
+}
+
+func TestMemoryDeadLetterSink_Capacity(t *testing.T) {
+	sink := NewMemoryDeadLetterSink(2)
+	for i := 1; i <= 3; i++ {
+		assert.NoError(t, sink.Put(DeadLetter{Attempts: i}))
+	}
+	letters := sink.Letters()
+	assert.Len(t, letters, 2)
+	assert.Equal(t, 2, letters[0].Attempts)
+	assert.Equal(t, 3, letters[1].Attempts)
+}
+
+func TestFileDeadLetterSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dead-letters.jsonl")
+	sink, err := NewFileDeadLetterSink(path)
+	assert.NoError(t, err)
+
+	wheel, advance := newFakeClockWheel(t, time.Millisecond*10,
+		WithDeadLetterSink(sink),
+		WithPanicHandler(func(interface{}) {}))
+	defer wheel.Stop()
+
+	_, err = wheel.NewTimeout(TimerTaskFunc(func(timeout Timeout) error {
+		return errors.New("failed")
+	}), time.Millisecond*10)
+	assert.NoError(t, err)
+	panicked, err := wheel.NewTimeoutWithOptions(TimerTaskFunc(func(timeout Timeout) error {
+		panic("boom")
+	}), time.Millisecond*30, WithKey("job"))
+	assert.NoError(t, err)
+	advance(time.Millisecond * 50)
+	assert.NoError(t, sink.Close())
+
+	file, err := os.Open(path)
+	assert.NoError(t, err)
+	defer file.Close()
+
+	var lines []FileDeadLetter
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		var line FileDeadLetter
+		assert.NoError(t, json.Unmarshal(scanner.Bytes(), &line))
+		lines = append(lines, line)
+	}
+	assert.NoError(t, scanner.Err())
+
+	assert.Len(t, lines, 2)
+	assert.Equal(t, "failed", lines[0].Error)
+	assert.Empty(t, lines[0].Panic)
+	assert.Equal(t, 1, lines[0].Attempts)
+	assert.Equal(t, "boom", lines[1].Panic)
+	assert.NotEmpty(t, lines[1].Stack)
+	assert.Equal(t, panicked.ID(), lines[1].TimeoutID)
+	assert.Equal(t, "job", lines[1].Key)
+}
+
+func TestFileDeadLetterSink_Periodic(t *testing.T) {
+	sink, err := NewFileDeadLetterSink(filepath.Join(t.TempDir(), "dead-letters.jsonl"))
+	assert.NoError(t, err)
+	defer sink.Close()
+
+	wheel, err := NewWheelTimer(time.Millisecond*10, 64,
+		WithDeadLetterSink(sink),
+		WithPanicHandler(func(interface{}) {}))
+	assert.NoError(t, err)
+	defer wheel.Stop()
+
+	// the letters are written while the worker reschedules the timeout.
+	timeout, err := wheel.NewPeriodicTimeout(TimerTaskFunc(func(timeout Timeout) error {
+		panic("boom")
+	}), time.Millisecond*10, time.Millisecond*10)
+	assert.NoError(t, err)
+	assert.Eventually(t, func() bool { return timeout.RunCount() >= 3 }, time.Second, time.Millisecond)
+	assert.True(t, timeout.Cancel())
+}
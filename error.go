@@ -15,7 +15,10 @@ limitations under the License.
 */
 package wheeltimer
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
 type timeoutError struct{}
 
@@ -32,4 +35,45 @@ var (
 
 	// ErrEmpty is returned when queue is empty
 	ErrEmpty = errors.New(`queue: empty`)
+
+	// ErrShuttingDown is returned when a timeout is added to a timer which is being shut down.
+	ErrShuttingDown = errors.New(`wheeltimer: shutting down`)
+
+	// ErrCancelled is the error of a timeout which has been cancelled.
+	ErrCancelled = errors.New(`wheeltimer: timeout cancelled`)
+
+	// ErrRejected is the error of a timeout whose task has been rejected by a RejectingExecutor.
+	ErrRejected = errors.New(`wheeltimer: task rejected by executor`)
+
+	// ErrKeyExists is returned by NewKeyedTimeout when the key is taken and the KeyPolicy is KeyPolicyReject.
+	ErrKeyExists = errors.New(`wheeltimer: key exists`)
 )
+
+// RunningTasksError is returned when the tasks of a stopped timer are still running after the context
+// passed to StopAndWait or Shutdown is done.
+type RunningTasksError struct {
+	// Running holds the timeouts whose tasks were still running.
+	Running []Timeout
+	// Err is the error of the context.
+	Err error
+}
+
+func (e *RunningTasksError) Error() string {
+	return fmt.Sprintf("wheeltimer: %d tasks still running: %v", len(e.Running), e.Err)
+}
+
+func (e *RunningTasksError) Unwrap() error {
+	return e.Err
+}
+
+// PanicError is the error of a timeout whose task panicked.
+type PanicError struct {
+	// Value is the value passed to panic.
+	Value interface{}
+	// Stack is the stack trace of the goroutine which panicked.
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("wheeltimer: task panicked: %v", e.Value)
+}
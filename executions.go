@@ -0,0 +1,72 @@
+package wheeltimer
+
+import (
+	"context"
+	"sync"
+)
+
+// executions tracks the tasks which have been handed to the executor and have not returned yet.
+type executions struct {
+	lock    sync.Mutex
+	running map[*WheelTimeout]int // a periodic timeout may be running more than once at a time
+	count   int
+	idleCh  chan struct{} // closed when count drops to zero, nil while count is zero
+}
+
+func (e *executions) add(timeout *WheelTimeout) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	if e.count == 0 {
+		e.idleCh = make(chan struct{})
+	}
+	if e.running == nil {
+		e.running = make(map[*WheelTimeout]int)
+	}
+	e.running[timeout]++
+	e.count++
+}
+
+func (e *executions) done(timeout *WheelTimeout) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	if n := e.running[timeout]; n > 1 {
+		e.running[timeout] = n - 1
+	} else {
+		delete(e.running, timeout)
+	}
+	e.count--
+	if e.count == 0 {
+		close(e.idleCh)
+		e.idleCh = nil
+	}
+}
+
+// wait blocks until no task is running or ctx is done. In the latter case it returns a RunningTasksError
+// listing the timeouts whose tasks are still running.
+func (e *executions) wait(ctx context.Context) error {
+	e.lock.Lock()
+	ch := e.idleCh
+	e.lock.Unlock()
+
+	if ch == nil {
+		return nil
+	}
+	select {
+	case <-ch:
+		return nil
+	case <-ctx.Done():
+	}
+
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	if e.count == 0 {
+		return nil
+	}
+	running := make([]Timeout, 0, len(e.running))
+	for timeout := range e.running {
+		running = append(running, timeout)
+	}
+	return &RunningTasksError{Running: running, Err: ctx.Err()}
+}
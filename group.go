@@ -0,0 +1,104 @@
+package wheeltimer
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// TimeoutGroup tracks related timeouts of a WheelTimer, so that they can be cancelled or waited for together.
+// A timeout joins a group when it is created with WithGroup or by TimeoutGroup.NewTimeout, and leaves it once it
+// has completed, that is it has been cancelled, or its task has run for the last time.
+type TimeoutGroup struct {
+	timer   *WheelTimer
+	lock    sync.Mutex
+	members map[*WheelTimeout]struct{}
+	idle    chan struct{} // closed when the last member leaves, created lazily by Wait
+}
+
+// NewGroup creates an empty TimeoutGroup for the timeouts of the timer.
+func (tw *WheelTimer) NewGroup() *TimeoutGroup {
+	return &TimeoutGroup{
+		timer:   tw,
+		members: make(map[*WheelTimeout]struct{}),
+	}
+}
+
+// WithGroup adds the timeout to the specified TimeoutGroup, which must have been created by the same timer.
+func WithGroup(group *TimeoutGroup) TimeoutOption {
+	return func(timeout *WheelTimeout) {
+		timeout.group = group
+	}
+}
+
+// NewTimeout schedules the specified TimerTask on the timer of the group like WheelTimer.NewTimeoutWithOptions, and
+// adds it to the group.
+func (g *TimeoutGroup) NewTimeout(task TimerTask, delay time.Duration, opts ...TimeoutOption) (Timeout, error) {
+	return g.timer.NewTimeoutWithOptions(task, delay, append(opts, WithGroup(g))...)
+}
+
+// Len is Returns the number of timeouts in the group which have not completed yet.
+func (g *TimeoutGroup) Len() int {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	return len(g.members)
+}
+
+// CancelAll cancels every timeout of the group, and returns the number of timeouts which have been cancelled.
+// Tasks which are already running are not interrupted, they stay in the group until they return.
+func (g *TimeoutGroup) CancelAll() int {
+	g.lock.Lock()
+	members := make([]*WheelTimeout, 0, len(g.members))
+	for timeout := range g.members {
+		members = append(members, timeout)
+	}
+	g.lock.Unlock()
+
+	cancelled := 0
+	for _, timeout := range members {
+		if timeout.Cancel() {
+			cancelled++
+		}
+	}
+	return cancelled
+}
+
+// Wait blocks until every timeout of the group has completed, including the ones added while it is waiting,
+// or until ctx is done, in which case it returns the error of ctx.
+func (g *TimeoutGroup) Wait(ctx context.Context) error {
+	g.lock.Lock()
+	if len(g.members) == 0 {
+		g.lock.Unlock()
+		return nil
+	}
+	if g.idle == nil {
+		g.idle = make(chan struct{})
+	}
+	idle := g.idle
+	g.lock.Unlock()
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// add makes timeout a member of the group, it is removed again when it completes.
+func (g *TimeoutGroup) add(timeout *WheelTimeout) {
+	g.lock.Lock()
+	g.members[timeout] = struct{}{}
+	g.lock.Unlock()
+}
+
+// remove removes timeout from the group, and wakes up the waiters when it has been the last member.
+func (g *TimeoutGroup) remove(timeout *WheelTimeout) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	delete(g.members, timeout)
+	if len(g.members) == 0 && g.idle != nil {
+		close(g.idle)
+		g.idle = nil
+	}
+}
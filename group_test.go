@@ -0,0 +1,74 @@
+package wheeltimer
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimeoutGroup(t *testing.T) {
+	wheel, advance := newFakeClockWheel(t, time.Millisecond*10)
+	defer wheel.Stop()
+
+	var fired atomic.Int32
+	task := TimerTaskFunc(func(timeout Timeout) error {
+		fired.Add(1)
+		return nil
+	})
+
+	t.Run("cleanup", func(t *testing.T) {
+		group := wheel.NewGroup()
+		for i := 1; i <= 3; i++ {
+			_, err := group.NewTimeout(task, time.Duration(i)*time.Millisecond*10)
+			assert.NoError(t, err)
+		}
+		periodic, err := wheel.NewPeriodicTimeout(task, time.Millisecond*10, time.Millisecond*10, WithGroup(group))
+		assert.NoError(t, err)
+		assert.Equal(t, 4, group.Len())
+
+		advance(time.Millisecond * 20)
+		assert.Equal(t, 3, group.Len())
+		advance(time.Millisecond * 20)
+		assert.Equal(t, 1, group.Len())
+		assert.True(t, periodic.Cancel())
+		assert.Equal(t, 0, group.Len())
+	})
+
+	t.Run("cancel all", func(t *testing.T) {
+		fired.Store(0)
+		group := wheel.NewGroup()
+		for i := 0; i < 5; i++ {
+			_, err := group.NewTimeout(task, time.Millisecond*20)
+			assert.NoError(t, err)
+		}
+		_, err := wheel.NewKeyedTimeout("key", task, time.Millisecond*20, WithGroup(group))
+		assert.NoError(t, err)
+
+		assert.Equal(t, 6, group.CancelAll())
+		assert.Equal(t, 0, group.Len())
+		assert.NoError(t, group.Wait(context.Background()))
+		advance(time.Millisecond * 30)
+		assert.Equal(t, int32(0), fired.Load())
+	})
+
+	t.Run("wait", func(t *testing.T) {
+		group := wheel.NewGroup()
+		_, err := group.NewTimeout(task, time.Millisecond*10)
+		assert.NoError(t, err)
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+		defer cancel()
+		assert.ErrorIs(t, group.Wait(ctx), context.DeadlineExceeded)
+
+		waited := make(chan error)
+		go func() {
+			waited <- group.Wait(context.Background())
+		}()
+		advance(time.Millisecond * 20)
+		assert.NoError(t, <-waited)
+		assert.Equal(t, 0, group.Len())
+	})
+}
@@ -0,0 +1,161 @@
+package wheeltimer
+
+import (
+	"fmt"
+	"log/slog"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// TimeoutRejectingExecutor is a TimeoutExecutor which may refuse a task. When the executor of a timer implements it,
+// the timer uses ExecuteTimeoutOrReject, and a rejected timeout completes with the error passed to reject.
+type TimeoutRejectingExecutor interface {
+	TimeoutExecutor
+	RejectingExecutor
+
+	// ExecuteTimeoutOrReject is Runs task, which runs the TimerTask of timeout, or calls reject exactly once if task
+	// will never be run.
+	ExecuteTimeoutOrReject(timeout Timeout, task func(), reject func(err error))
+}
+
+// InlineExecutor runs the tasks directly on the worker goroutine of the timer, which saves starting a goroutine for
+// tiny tasks such as flag flips or channel sends. Every task is timed against a budget on the Clock of its timer: a
+// task which exceeds it stalls the wheel, so it is logged and counted, and if a fallback executor is set, the later
+// runs of the same kind of task are migrated to the fallback. A watchdog logs and counts a task as soon as it is still
+// running at the end of its budget, so that a task which never returns does not stall the wheel unnoticed.
+//
+// The kind of a task is the type of its TimerTask, or the function of a TimerTaskFunc or ContextTimerTaskFunc, so
+// that the closures created by the same function literal are migrated together.
+type InlineExecutor struct {
+	budget   time.Duration
+	fallback Executor
+	logger   *slog.Logger
+
+	overruns atomic.Int64
+	migrated sync.Map // of task kinds which are run by fallback
+}
+
+// taskKind identifies the tasks which are migrated together.
+type taskKind struct {
+	typ reflect.Type
+	fn  uintptr
+}
+
+// NewInlineExecutor creates an InlineExecutor with the given time budget per task. If fallback is not nil, the kinds
+// of tasks which have exceeded the budget are handed to it from then on. A nil logger logs to slog.Default.
+func NewInlineExecutor(budget time.Duration, fallback Executor, logger *slog.Logger) (*InlineExecutor, error) {
+	if budget <= 0 {
+		return nil, fmt.Errorf("budget: %d (expected: > 0)", budget)
+	}
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &InlineExecutor{
+		budget:   budget,
+		fallback: fallback,
+		logger:   logger,
+	}, nil
+}
+
+// Execute runs task on the current goroutine. A task without a timeout can not be migrated, it is only timed
+// on the real clock.
+func (e *InlineExecutor) Execute(task func()) {
+	e.runTimed(task, nil)
+}
+
+// ExecuteOrReject runs task on the current goroutine like Execute, so it never rejects it.
+func (e *InlineExecutor) ExecuteOrReject(task func(), reject func(err error)) {
+	e.runTimed(task, nil)
+}
+
+func (e *InlineExecutor) ExecuteTimeout(timeout Timeout, task func()) {
+	e.ExecuteTimeoutOrReject(timeout, task, nil)
+}
+
+// ExecuteTimeoutOrReject runs task on the current goroutine, or hands it over to the fallback executor if its kind
+// has been migrated. Only the fallback executor may reject it.
+func (e *InlineExecutor) ExecuteTimeoutOrReject(timeout Timeout, task func(), reject func(err error)) {
+	if e.fallback != nil {
+		if _, ok := e.migrated.Load(kindOf(timeout.Task())); ok {
+			executeOn(e.fallback, timeout, task, reject)
+			return
+		}
+	}
+	e.runTimed(task, timeout)
+}
+
+func (e *InlineExecutor) runTimed(task func(), timeout Timeout) {
+	attrs := []any{"budget", e.budget}
+	var kind taskKind
+	if timeout != nil {
+		kind = kindOf(timeout.Task())
+		attrs = append(attrs, "task", kind)
+	}
+
+	clock := clockOf(timeout)
+	start := clock.Now()
+	stopWatchdog := clock.AfterFunc(e.budget, func() {
+		e.overruns.Add(1)
+		e.logger.Warn("[wheeltimer] inline task is still running after its budget", attrs...)
+	})
+	task()
+	elapsed := clock.Since(start)
+	if stopWatchdog() {
+		if elapsed <= e.budget {
+			return
+		}
+		e.overruns.Add(1)
+	}
+
+	if timeout == nil {
+		e.logger.Warn("[wheeltimer] inline task exceeded its budget", append(attrs, "elapsed", elapsed)...)
+		return
+	}
+	migrate := e.fallback != nil
+	if migrate {
+		e.migrated.Store(kind, struct{}{})
+	}
+	e.logger.Warn("[wheeltimer] inline task exceeded its budget", append(attrs, "elapsed", elapsed,
+		"migrated", migrate)...)
+}
+
+// Overruns is Returns the number of tasks which have exceeded the budget.
+func (e *InlineExecutor) Overruns() int64 {
+	return e.overruns.Load()
+}
+
+// Migrated is Returns the number of kinds of tasks which have been migrated to the fallback executor.
+func (e *InlineExecutor) Migrated() int {
+	n := 0
+	e.migrated.Range(func(_, _ interface{}) bool {
+		n++
+		return true
+	})
+	return n
+}
+
+// clockOf is Returns the Clock of the timer of timeout, on which its budget is measured, or the real clock for the
+// tasks without a timer.
+func clockOf(timeout Timeout) Clock {
+	if timeout, ok := timeout.(*WheelTimeout); ok && timeout.timer != nil {
+		return timeout.timer.clock
+	}
+	return realClock{}
+}
+
+func kindOf(task TimerTask) taskKind {
+	kind := taskKind{typ: reflect.TypeOf(task)}
+	if kind.typ != nil && kind.typ.Kind() == reflect.Func {
+		kind.fn = reflect.ValueOf(task).Pointer()
+	}
+	return kind
+}
+
+func (k taskKind) String() string {
+	if k.fn != 0 {
+		return fmt.Sprintf("%v@%#x", k.typ, k.fn)
+	}
+	return fmt.Sprint(k.typ)
+}
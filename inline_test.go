@@ -0,0 +1,233 @@
+package wheeltimer
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type countingExecutor struct {
+	executed atomic.Int32
+}
+
+func (e *countingExecutor) Execute(task func()) {
+	e.executed.Add(1)
+	task()
+}
+
+func TestInlineExecutor(t *testing.T) {
+	fallback := &countingExecutor{}
+	executor, err := NewInlineExecutor(time.Millisecond*5, fallback, nil)
+	assert.NoError(t, err)
+	wheel, advance := newFakeClockWheel(t, time.Millisecond*10, WithExecutor(executor))
+	defer wheel.Stop()
+	clock := wheel.clock.(*FakeClock)
+
+	// the budget is measured on the clock of the timer.
+	var slowRuns, fastRuns atomic.Int32
+	slow := func() TimerTask {
+		return TimerTaskFunc(func(timeout Timeout) error {
+			if slowRuns.Add(1) == 1 {
+				clock.Advance(time.Millisecond * 10)
+			}
+			return nil
+		})
+	}
+	fast := TimerTaskFunc(func(timeout Timeout) error {
+		fastRuns.Add(1)
+		return nil
+	})
+
+	_, err = wheel.NewTimeout(slow(), time.Millisecond*10)
+	assert.NoError(t, err)
+	_, err = wheel.NewTimeout(fast, time.Millisecond*10)
+	assert.NoError(t, err)
+	advance(time.Millisecond * 20)
+	assert.Equal(t, int32(1), slowRuns.Load())
+	assert.Equal(t, int32(1), fastRuns.Load())
+	assert.Eventually(t, func() bool { return executor.Overruns() == 1 }, time.Second, time.Millisecond)
+	assert.Equal(t, 1, executor.Migrated())
+	assert.Equal(t, int32(0), fallback.executed.Load())
+
+	// another closure of the same function literal is migrated as well.
+	_, err = wheel.NewTimeout(slow(), time.Millisecond*10)
+	assert.NoError(t, err)
+	_, err = wheel.NewTimeout(fast, time.Millisecond*10)
+	assert.NoError(t, err)
+	advance(time.Millisecond * 20)
+	assert.Equal(t, int32(2), slowRuns.Load())
+	assert.Equal(t, int32(2), fastRuns.Load())
+	assert.Equal(t, int32(1), fallback.executed.Load())
+
+	_, err = NewInlineExecutor(0, nil, nil)
+	assert.Error(t, err)
+}
+
+func TestInlineExecutor_NoFallback(t *testing.T) {
+	executor, err := NewInlineExecutor(time.Millisecond, nil, nil)
+	assert.NoError(t, err)
+
+	ran := 0
+	executor.Execute(func() {
+		ran++
+		time.Sleep(time.Millisecond * 5)
+	})
+	executor.ExecuteTimeout(&WheelTimeout{task: TimerTaskFunc(func(timeout Timeout) error {
+		return nil
+	})}, func() {
+		ran++
+		time.Sleep(time.Millisecond * 5)
+	})
+	assert.Equal(t, 2, ran)
+	assert.Equal(t, int64(2), executor.Overruns())
+	assert.Equal(t, 0, executor.Migrated())
+}
+
+func TestInlineExecutor_RejectingFallback(t *testing.T) {
+	pool, err := NewPoolExecutor(1, 0, RejectPolicyAbort)
+	assert.NoError(t, err)
+	defer pool.Close()
+	executor, err := NewInlineExecutor(time.Millisecond*5, pool, nil)
+	assert.NoError(t, err)
+	wheel, advance := newFakeClockWheel(t, time.Millisecond*10, WithExecutor(executor))
+	clock := wheel.clock.(*FakeClock)
+
+	var runs atomic.Int32
+	slow := func() TimerTask {
+		return TimerTaskFunc(func(timeout Timeout) error {
+			if runs.Add(1) == 1 {
+				clock.Advance(time.Millisecond * 10)
+			}
+			return nil
+		})
+	}
+	_, err = wheel.NewTimeout(slow(), time.Millisecond*10)
+	assert.NoError(t, err)
+	advance(time.Millisecond * 20)
+	assert.Equal(t, 1, executor.Migrated())
+
+	// the pool rejects the tasks it has no idle worker for, which completes their timeouts.
+	var timeouts []Timeout
+	for i := 0; i < 3; i++ {
+		timeout, err := wheel.NewTimeout(slow(), time.Millisecond*10)
+		assert.NoError(t, err)
+		timeouts = append(timeouts, timeout)
+	}
+	advance(time.Millisecond * 20)
+	for _, timeout := range timeouts {
+		select {
+		case <-timeout.Done():
+		case <-time.After(time.Second):
+			assert.Fail(t, "the timeout has not completed", timeout.State())
+		}
+		if timeout.State() == TimeoutStateFailed {
+			assert.ErrorIs(t, timeout.Err(), ErrRejected)
+		}
+	}
+	_, err = wheel.StopAndWait(context.Background())
+	assert.NoError(t, err)
+}
+
+func TestInlineExecutor_Watchdog(t *testing.T) {
+	executor, err := NewInlineExecutor(time.Millisecond*5, nil, nil)
+	assert.NoError(t, err)
+
+	release := make(chan struct{})
+	returned := make(chan struct{})
+	go func() {
+		defer close(returned)
+		executor.Execute(func() {
+			<-release
+		})
+	}()
+
+	// the task is counted while it is still running.
+	assert.Eventually(t, func() bool { return executor.Overruns() == 1 }, time.Second, time.Millisecond)
+	close(release)
+	<-returned
+	assert.Equal(t, int64(1), executor.Overruns())
+
+	// on a timer, the watchdog runs on the clock of the timer.
+	wheel, advance := newFakeClockWheel(t, time.Millisecond*10, WithExecutor(executor))
+	defer wheel.Stop()
+	clock := wheel.clock.(*FakeClock)
+	release = make(chan struct{})
+	running := make(chan struct{})
+	_, err = wheel.NewTimeout(TimerTaskFunc(func(timeout Timeout) error {
+		close(running)
+		<-release
+		return nil
+	}), time.Millisecond*10)
+	assert.NoError(t, err)
+	advance(time.Millisecond * 10)
+	clock.Advance(time.Millisecond * 10)
+	<-running
+	time.Sleep(time.Millisecond * 10)
+	assert.Equal(t, int64(1), executor.Overruns())
+	clock.Advance(time.Millisecond * 5)
+	assert.Eventually(t, func() bool { return executor.Overruns() == 2 }, time.Second, time.Millisecond)
+	close(release)
+}
+
+func TestInlineExecutor_SaturatedRing(t *testing.T) {
+	executor, err := NewInlineExecutor(time.Second, nil, nil)
+	assert.NoError(t, err)
+	policy := &RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond * 10}
+	wheel, advance := newFakeClockWheel(t, time.Millisecond*10, WithExecutor(executor), WithRingBufferSize(8),
+		WithRetryPolicy(policy))
+	defer wheel.Stop()
+
+	var runs atomic.Int32
+	delayed := TimerTaskFunc(func(timeout Timeout) error {
+		runs.Add(1)
+		return nil
+	})
+	failing := TimerTaskFunc(func(timeout Timeout) error {
+		if timeout.Attempt() == 1 {
+			return errors.New("failed")
+		}
+		return nil
+	})
+
+	// more timeouts than the ring buffer holds expire in the same tick, and are put back by the worker itself.
+	var periodic, retried []Timeout
+	for i := 0; i < 6; i++ {
+		for j := 0; j < 2; j++ {
+			timeout, err := wheel.NewFixedDelayTimeout(delayed, time.Millisecond*time.Duration(60-i*10),
+				time.Millisecond*10)
+			assert.NoError(t, err)
+			periodic = append(periodic, timeout)
+			attempted, err := wheel.NewTimeout(failing, time.Millisecond*time.Duration(60-i*10))
+			assert.NoError(t, err)
+			retried = append(retried, attempted)
+		}
+		advance(time.Millisecond * 10)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		advance(time.Millisecond * 60)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second * 5):
+		assert.FailNow(t, "the worker is stuck on the full ring buffer")
+	}
+	assert.GreaterOrEqual(t, runs.Load(), int32(2*len(periodic)))
+	for _, timeout := range retried {
+		assert.Equal(t, 2, timeout.Attempt())
+		assert.Equal(t, TimeoutStateSucceeded, timeout.State())
+	}
+	for i, timeout := range periodic {
+		assert.True(t, timeout.Cancel())
+		if i%4 == 3 {
+			advance(time.Millisecond * 10)
+		}
+	}
+	assert.Equal(t, int64(0), wheel.PendingTimeouts())
+}
@@ -0,0 +1,119 @@
+package wheeltimer
+
+import (
+	"sync"
+	"time"
+)
+
+// KeyPolicy decides what NewKeyedTimeout does when a timeout with the same key is still pending or running.
+type KeyPolicy int
+
+const (
+	// KeyPolicyReplace cancels the existing timeout and schedules the new one in its place.
+	KeyPolicyReplace KeyPolicy = iota
+	// KeyPolicyKeep keeps the existing timeout and returns it instead of scheduling the new one.
+	KeyPolicyKeep
+	// KeyPolicyReject keeps the existing timeout and fails with ErrKeyExists.
+	KeyPolicyReject
+)
+
+// WithKeyPolicy sets the KeyPolicy of a timeout created by NewKeyedTimeout, KeyPolicyReplace by default.
+func WithKeyPolicy(policy KeyPolicy) TimeoutOption {
+	return func(timeout *WheelTimeout) {
+		timeout.keyPolicy = policy
+	}
+}
+
+// keyedTimeouts indexes the timeouts created by NewKeyedTimeout by their keys. A timeout is removed once it has
+// completed, so that it never outlives its place in the wheel.
+type keyedTimeouts struct {
+	lock     sync.Mutex
+	timeouts map[string]*WheelTimeout
+}
+
+func (k *keyedTimeouts) get(key string) *WheelTimeout {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+	return k.timeouts[key]
+}
+
+// remove removes timeout from the index, unless its key has been taken over by another timeout.
+func (k *keyedTimeouts) remove(timeout *WheelTimeout) {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+	if k.timeouts[timeout.key] == timeout {
+		delete(k.timeouts, timeout.key)
+	}
+}
+
+// NewKeyedTimeout schedules the specified TimerTask for one-time execution after the specified delay, and registers
+// it under key until it has completed. If the key is taken by a timeout which has not completed yet, the KeyPolicy
+// set by WithKeyPolicy decides whether that timeout is replaced, kept or the call fails with ErrKeyExists.
+// A timeout which has already started running is not stopped by a replacement, but loses its key.
+func (tw *WheelTimer) NewKeyedTimeout(key string, task TimerTask, delay time.Duration, opts ...TimeoutOption) (Timeout, error) {
+	timeout := newWheelTimeout(tw, task, 0)
+	timeout.apply(opts)
+	timeout.key = key
+
+	tw.keys.lock.Lock()
+	existing := tw.keys.timeouts[key]
+	if existing != nil {
+		switch timeout.keyPolicy {
+		case KeyPolicyKeep:
+			tw.keys.lock.Unlock()
+			return existing, nil
+		case KeyPolicyReject:
+			tw.keys.lock.Unlock()
+			return nil, ErrKeyExists
+		}
+	}
+	if tw.keys.timeouts == nil {
+		tw.keys.timeouts = make(map[string]*WheelTimeout)
+	}
+	tw.keys.timeouts[key] = timeout
+	tw.keys.lock.Unlock()
+
+	// the timeout is scheduled without holding the lock, because a full ring buffer waits for the worker, which may
+	// be completing another keyed timeout.
+	if err := tw.schedule(timeout, delay); err != nil {
+		tw.keys.lock.Lock()
+		if tw.keys.timeouts[key] == timeout {
+			if existing != nil && !existing.State().IsDone() {
+				tw.keys.timeouts[key] = existing
+			} else {
+				delete(tw.keys.timeouts, key)
+			}
+		}
+		tw.keys.lock.Unlock()
+		return nil, err
+	}
+
+	// the hooks of a completion may run on the goroutine which calls Cancel, so they are set up and the replaced
+	// timeout is cancelled without holding the lock.
+	timeout.afterDone(func() {
+		tw.keys.remove(timeout)
+	})
+	if existing != nil {
+		existing.Cancel()
+	}
+	return timeout, nil
+}
+
+// CancelKey cancels the timeout registered under key, see Timeout.Cancel. It returns false if there is no such
+// timeout, or it has already expired.
+func (tw *WheelTimer) CancelKey(key string) bool {
+	timeout := tw.keys.get(key)
+	if timeout == nil {
+		return false
+	}
+	return timeout.Cancel()
+}
+
+// GetKey is Returns the timeout registered under key, which is pending or running.
+func (tw *WheelTimer) GetKey(key string) (Timeout, bool) {
+	timeout := tw.keys.get(key)
+	if timeout == nil {
+		return nil, false
+	}
+	return timeout, true
+}
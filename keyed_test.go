@@ -0,0 +1,102 @@
+package wheeltimer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyedTimeout(t *testing.T) {
+	wheel, advance := newFakeClockWheel(t, time.Millisecond*10)
+	defer wheel.Stop()
+
+	var fired []string
+	task := func(name string) TimerTask {
+		return TimerTaskFunc(func(timeout Timeout) error {
+			fired = append(fired, name)
+			return nil
+		})
+	}
+
+	t.Run("replace", func(t *testing.T) {
+		first, err := wheel.NewKeyedTimeout("session", task("first"), time.Millisecond*10)
+		assert.NoError(t, err)
+		second, err := wheel.NewKeyedTimeout("session", task("second"), time.Millisecond*20)
+		assert.NoError(t, err)
+		assert.True(t, first.IsCancelled())
+
+		got, ok := wheel.GetKey("session")
+		assert.True(t, ok)
+		assert.Same(t, second, got)
+
+		advance(time.Millisecond * 30)
+		assert.Equal(t, []string{"second"}, fired)
+		_, ok = wheel.GetKey("session")
+		assert.False(t, ok)
+		assert.Equal(t, int64(0), wheel.PendingTimeouts())
+	})
+
+	t.Run("keep", func(t *testing.T) {
+		fired = nil
+		first, err := wheel.NewKeyedTimeout("session", task("first"), time.Millisecond*10)
+		assert.NoError(t, err)
+		second, err := wheel.NewKeyedTimeout("session", task("second"), time.Millisecond*10, WithKeyPolicy(KeyPolicyKeep))
+		assert.NoError(t, err)
+		assert.Same(t, first, second)
+
+		advance(time.Millisecond * 20)
+		assert.Equal(t, []string{"first"}, fired)
+	})
+
+	t.Run("reject", func(t *testing.T) {
+		fired = nil
+		_, err := wheel.NewKeyedTimeout("session", task("first"), time.Millisecond*10)
+		assert.NoError(t, err)
+		_, err = wheel.NewKeyedTimeout("session", task("second"), time.Millisecond*10, WithKeyPolicy(KeyPolicyReject))
+		assert.ErrorIs(t, err, ErrKeyExists)
+
+		advance(time.Millisecond * 20)
+		assert.Equal(t, []string{"first"}, fired)
+
+		// the key is free again once the timeout has completed.
+		_, err = wheel.NewKeyedTimeout("session", task("third"), time.Millisecond*10, WithKeyPolicy(KeyPolicyReject))
+		assert.NoError(t, err)
+		advance(time.Millisecond * 20)
+		assert.Equal(t, []string{"first", "third"}, fired)
+	})
+
+	t.Run("cancel", func(t *testing.T) {
+		fired = nil
+		timeout, err := wheel.NewKeyedTimeout("session", task("first"), time.Millisecond*10)
+		assert.NoError(t, err)
+		assert.True(t, wheel.CancelKey("session"))
+		assert.True(t, timeout.IsCancelled())
+		assert.False(t, wheel.CancelKey("session"))
+		_, ok := wheel.GetKey("session")
+		assert.False(t, ok)
+
+		advance(time.Millisecond * 20)
+		assert.Empty(t, fired)
+		assert.Equal(t, int64(0), wheel.PendingTimeouts())
+	})
+}
+
+func TestKeyedTimeout_Stop(t *testing.T) {
+	wheel, _ := newFakeClockWheel(t, time.Millisecond*10)
+
+	_, err := wheel.NewKeyedTimeout("session", TimerTaskFunc(func(timeout Timeout) error {
+		return nil
+	}), time.Millisecond*10)
+	assert.NoError(t, err)
+	assert.Len(t, wheel.Stop(), 1)
+
+	_, ok := wheel.GetKey("session")
+	assert.False(t, ok)
+	_, err = wheel.NewKeyedTimeout("session", TimerTaskFunc(func(timeout Timeout) error {
+		return nil
+	}), time.Millisecond*10)
+	assert.Error(t, err)
+	_, ok = wheel.GetKey("session")
+	assert.False(t, ok)
+}
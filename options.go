@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"log/slog"
 	"runtime/debug"
+	"time"
 )
 
 const (
@@ -19,6 +20,14 @@ type option struct {
 	logger             *slog.Logger
 	ringBufferSize     uint64
 	ringBufferOptions  []RingOption
+	hierarchical       bool
+	clock              Clock
+	wallClockThreshold time.Duration
+	retryPolicy        *RetryPolicy
+	deadLetterSink     DeadLetterSink
+	timeLimit          time.Duration
+	overrunHandler     OverrunHandler
+	errorHandler       ErrorHandler
 }
 
 type WheelTimerOption func(*option)
@@ -35,6 +44,14 @@ func WithPanicHandler(handler PanicHandler) WheelTimerOption {
 	}
 }
 
+// WithErrorHandler sets the handler of the errors returned by the tasks. By default they are logged as a warning,
+// with the type of the task, the ID of the timeout, the deadline the run was scheduled for and the time it started.
+func WithErrorHandler(handler ErrorHandler) WheelTimerOption {
+	return func(o *option) {
+		o.errorHandler = handler
+	}
+}
+
 func WithLogger(logger *slog.Logger) WheelTimerOption {
 	return func(o *option) {
 		o.logger = logger
@@ -59,12 +76,94 @@ func WithRingBufferOptions(opts ...RingOption) WheelTimerOption {
 	}
 }
 
+// WithHierarchicalWheel enables the hierarchical mode. Instead of counting down the rounds of a timeout
+// that lies beyond one revolution of the wheel on every revolution, such a timeout is held by an overflow
+// wheel with coarser ticks and cascaded down when it gets close to its deadline, so the work per tick only
+// depends on the timeouts which are about to expire.
+func WithHierarchicalWheel(enabled bool) WheelTimerOption {
+	return func(o *option) {
+		o.hierarchical = enabled
+	}
+}
+
+// WithClock sets the source of time of the timer, see FakeClock for driving a timer in tests.
+func WithClock(clock Clock) WheelTimerOption {
+	return func(o *option) {
+		o.clock = clock
+	}
+}
+
+// WithWallClockCheck makes the worker compare the wall clock with the monotonic clock on every tick. When the
+// difference between them changes by more than threshold, because the system clock has been set, the timeouts
+// scheduled for a wall clock instant by NewTimeoutAt or a cron expression are moved to their new deadlines.
+func WithWallClockCheck(threshold time.Duration) WheelTimerOption {
+	return func(o *option) {
+		o.wallClockThreshold = threshold
+	}
+}
+
+// WithRetryPolicy sets the RetryPolicy of the one-shot timeouts of the timer which do not have their own,
+// see WithTimeoutRetryPolicy.
+func WithRetryPolicy(policy *RetryPolicy) WheelTimerOption {
+	return func(o *option) {
+		o.retryPolicy = policy
+	}
+}
+
+// WithDeadLetterSink sets the DeadLetterSink which receives the tasks which have failed for good, in addition to
+// the PanicHandler and the logger.
+func WithDeadLetterSink(sink DeadLetterSink) WheelTimerOption {
+	return func(o *option) {
+		o.deadLetterSink = sink
+	}
+}
+
+// WithTimeLimit sets the time limit of the tasks of the timer which do not have their own, see
+// WithTimeoutTimeLimit. Zero, the default, means no limit.
+func WithTimeLimit(limit time.Duration) WheelTimerOption {
+	return func(o *option) {
+		o.timeLimit = limit
+	}
+}
+
+// WithOverrunHandler sets the handler which is told about the tasks which run longer than their time limit. By
+// default the overruns are logged.
+func WithOverrunHandler(handler OverrunHandler) WheelTimerOption {
+	return func(o *option) {
+		o.overrunHandler = handler
+	}
+}
+
+// TimeoutOption configures a single timeout when it is created.
+type TimeoutOption func(*WheelTimeout)
+
+// WithTimeoutRetryPolicy sets the RetryPolicy of the timeout, overriding the one of the timer. A nil policy
+// disables retries.
+func WithTimeoutRetryPolicy(policy *RetryPolicy) TimeoutOption {
+	return func(timeout *WheelTimeout) {
+		timeout.retryPolicy = policy
+	}
+}
+
+// WithTimeoutTimeLimit sets the time limit of each run of the task of the timeout, overriding the one of the timer.
+// A ContextTimerTask is run with a context which is cancelled at the limit, and every task which runs longer is
+// reported to the OverrunHandler. Zero means no limit.
+func WithTimeoutTimeLimit(limit time.Duration) TimeoutOption {
+	return func(timeout *WheelTimeout) {
+		timeout.timeLimit = limit
+	}
+}
+
 type Executor interface {
 	Execute(task func())
 }
 
 type PanicHandler func(interface{})
 
+// ErrorHandler is called with the error returned by a run of the task of a timeout, or the error of the executor if
+// it has rejected the run. Panics are passed to the PanicHandler instead.
+type ErrorHandler func(timeout Timeout, err error)
+
 type defaultExecutor struct{}
 
 func (d *defaultExecutor) Execute(task func()) {
@@ -81,5 +180,6 @@ func newDefaultOption() *option {
 		logger:             logger,
 		maxPendingTimeouts: DefaultMaxPendingTimeouts,
 		ringBufferSize:     DefaultRingBufferSize,
+		clock:              realClock{},
 	}
 }
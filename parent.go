@@ -0,0 +1,102 @@
+package wheeltimer
+
+import (
+	"sync"
+)
+
+// WithParent makes the timeout a child of parent. Like a context derived from another one, a child is cancelled
+// when its parent is cancelled or fires, that is when a one-shot parent expires or a periodic parent will not be
+// run again. A child of a parent which has already been cancelled or fired is cancelled as soon as it is scheduled.
+// The parent must be a Timeout created by a WheelTimer, or a Future of one.
+func WithParent(parent Timeout) TimeoutOption {
+	return func(timeout *WheelTimeout) {
+		timeout.parent = unwrapTimeout(parent)
+	}
+}
+
+// unwrapTimeout is Returns the WheelTimeout behind a handle, or nil if it is not backed by one.
+func unwrapTimeout(timeout Timeout) *WheelTimeout {
+	switch t := timeout.(type) {
+	case *WheelTimeout:
+		return t
+	case interface{ unwrap() Timeout }:
+		return unwrapTimeout(t.unwrap())
+	default:
+		return nil
+	}
+}
+
+// children holds the child timeouts of a timeout which have not completed yet.
+type children struct {
+	lock     sync.Mutex
+	timeouts map[*WheelTimeout]struct{}
+	done     bool // whether the timeout has been cancelled or fired, no more children are accepted
+}
+
+// addChild registers child, it reports false if the timeout has already been cancelled or fired.
+func (timeout *WheelTimeout) addChild(child *WheelTimeout) bool {
+	c := &timeout.children
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.done {
+		return false
+	}
+	if c.timeouts == nil {
+		c.timeouts = make(map[*WheelTimeout]struct{})
+	}
+	c.timeouts[child] = struct{}{}
+	return true
+}
+
+func (timeout *WheelTimeout) removeChild(child *WheelTimeout) {
+	c := &timeout.children
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	delete(c.timeouts, child)
+}
+
+// cancelChildren cancels the children of a timeout which has been cancelled or fired. On the worker goroutine, the
+// children are cancelled with cancelOnWorker, since Cancel may wait for the worker.
+func (timeout *WheelTimeout) cancelChildren(onWorker bool) {
+	c := &timeout.children
+	c.lock.Lock()
+	timeouts := c.timeouts
+	c.timeouts = nil
+	c.done = true
+	c.lock.Unlock()
+
+	for child := range timeouts {
+		if onWorker {
+			child.cancelOnWorker()
+		} else {
+			child.Cancel()
+		}
+	}
+}
+
+// cancelOnWorker cancels the timeout like Cancel, on the worker goroutine. The worker is the only goroutine which
+// drains cancelledTimeouts, so it must not wait for room in it, and releases the timeout by the next
+// processCancelledTasks instead. The timeout is not released right away, since the worker may be walking the
+// bucket which holds it.
+func (timeout *WheelTimeout) cancelOnWorker() {
+	if !timeout.state.CompareAndSwap(int32(TimeoutStateScheduled), int32(TimeoutStateCancelled)) {
+		return
+	}
+	timeout.releaseContext()
+	timeout.complete(ErrCancelled)
+	timeout.cancelChildren(true)
+	tw := timeout.timer
+	tw.cancelledOnWorker = append(tw.cancelledOnWorker, timeout)
+}
+
+// attachToParent registers a timeout which has just been scheduled with its parent.
+func (timeout *WheelTimeout) attachToParent() {
+	parent := timeout.parent
+	if !parent.addChild(timeout) {
+		timeout.Cancel()
+		return
+	}
+	timeout.afterDone(func() {
+		parent.removeChild(timeout)
+	})
+}
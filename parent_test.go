@@ -0,0 +1,117 @@
+package wheeltimer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParentTimeout(t *testing.T) {
+	wheel, advance := newFakeClockWheel(t, time.Millisecond*10)
+	defer wheel.Stop()
+
+	var fired []string
+	task := func(name string) TimerTask {
+		return TimerTaskFunc(func(timeout Timeout) error {
+			fired = append(fired, name)
+			return nil
+		})
+	}
+
+	t.Run("cancel", func(t *testing.T) {
+		fired = nil
+		parent, err := wheel.NewTimeout(task("parent"), time.Millisecond*50)
+		assert.NoError(t, err)
+		child, err := wheel.NewTimeoutWithOptions(task("child"), time.Millisecond*20, WithParent(parent))
+		assert.NoError(t, err)
+		grandchild, err := wheel.NewTimeoutWithOptions(task("grandchild"), time.Millisecond*20, WithParent(child))
+		assert.NoError(t, err)
+
+		assert.True(t, parent.Cancel())
+		assert.True(t, child.IsCancelled())
+		assert.True(t, grandchild.IsCancelled())
+		advance(time.Millisecond * 60)
+		assert.Empty(t, fired)
+		assert.Equal(t, int64(0), wheel.PendingTimeouts())
+	})
+
+	t.Run("fire", func(t *testing.T) {
+		fired = nil
+		handshake, err := wheel.NewTimeout(task("handshake"), time.Millisecond*30)
+		assert.NoError(t, err)
+		early, err := wheel.NewTimeoutWithOptions(task("early"), time.Millisecond*10, WithParent(handshake))
+		assert.NoError(t, err)
+		late, err := wheel.NewTimeoutWithOptions(task("late"), time.Millisecond*60, WithParent(handshake))
+		assert.NoError(t, err)
+
+		advance(time.Millisecond * 20)
+		assert.Equal(t, []string{"early"}, fired)
+		assert.Len(t, unwrapTimeout(handshake).children.timeouts, 1)
+		advance(time.Millisecond * 30)
+		assert.Equal(t, []string{"early", "handshake"}, fired)
+		assert.Equal(t, TimeoutStateSucceeded, early.State())
+		assert.True(t, late.IsCancelled())
+	})
+
+	t.Run("finished parent", func(t *testing.T) {
+		fired = nil
+		parent, err := NewTimeoutFunc(wheel, func(timeout Timeout) (int, error) {
+			return 1, nil
+		}, time.Millisecond*10)
+		assert.NoError(t, err)
+		advance(time.Millisecond * 20)
+		assert.True(t, parent.IsExpired())
+
+		child, err := wheel.NewTimeoutWithOptions(task("child"), time.Millisecond*10, WithParent(parent))
+		assert.NoError(t, err)
+		assert.True(t, child.IsCancelled())
+		advance(time.Millisecond * 20)
+		assert.Empty(t, fired)
+	})
+
+	t.Run("periodic parent", func(t *testing.T) {
+		fired = nil
+		parent, err := wheel.NewPeriodicTimeout(task("parent"), time.Millisecond*10, time.Millisecond*10)
+		assert.NoError(t, err)
+		child, err := wheel.NewTimeoutWithOptions(task("child"), time.Millisecond*100, WithParent(parent))
+		assert.NoError(t, err)
+
+		// the runs of a periodic parent do not cancel its children, only its end does.
+		advance(time.Millisecond * 30)
+		assert.Equal(t, TimeoutStateScheduled, child.State())
+		assert.True(t, parent.Cancel())
+		assert.True(t, child.IsCancelled())
+	})
+}
+
+func TestParentTimeout_ManyChildren(t *testing.T) {
+	// the parent has more children than the ring buffer of the cancelled timeouts holds.
+	wheel, advance := newFakeClockWheel(t, time.Millisecond*10, WithRingBufferSize(8), WithMaxPendingTimeouts(0))
+
+	parent, err := wheel.NewTimeout(TimerTaskFunc(func(timeout Timeout) error {
+		return nil
+	}), time.Millisecond*100)
+	assert.NoError(t, err)
+	var children []Timeout
+	for i := 0; i < 8; i++ {
+		for j := 0; j < 4; j++ {
+			child, err := wheel.NewTimeoutWithOptions(TimerTaskFunc(func(timeout Timeout) error {
+				return nil
+			}), time.Hour, WithParent(parent))
+			assert.NoError(t, err)
+			children = append(children, child)
+		}
+		// let the worker drain the timeouts ring buffer.
+		advance(time.Millisecond * 10)
+	}
+
+	advance(time.Millisecond * 30)
+	assert.Equal(t, TimeoutStateSucceeded, parent.State())
+	for _, child := range children {
+		assert.True(t, child.IsCancelled())
+	}
+	advance(time.Millisecond * 10)
+	assert.Equal(t, int64(0), wheel.PendingTimeouts())
+	assert.Empty(t, wheel.Stop())
+}
@@ -0,0 +1,187 @@
+package wheeltimer
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/adol1111/wheeltimer/cron"
+)
+
+// PeriodicTimeout is the handle of a TimerTask scheduled for repeated execution.
+type PeriodicTimeout interface {
+	Timeout
+
+	// RunCount is Returns the number of runs of the TimerTask that have completed.
+	RunCount() int64
+
+	// LastError is Returns the error returned by the last completed run, or nil if it succeeded.
+	LastError() error
+
+	// LastCompletion is Returns the time at which the last run completed, or the zero time if none has.
+	LastCompletion() time.Time
+}
+
+type periodic struct {
+	period     time.Duration
+	fixedDelay bool
+	schedule   cron.Schedule
+	next       time.Time // the wall clock time of the next run of schedule
+
+	runs           atomic.Int64
+	running        atomic.Int32 // the number of runs which have been handed to the executor and not returned yet
+	lock           sync.Mutex
+	lastErr        error
+	lastCompletion time.Time
+}
+
+func (p *periodic) completed(err error, now time.Time) {
+	p.lock.Lock()
+	p.lastErr = err
+	p.lastCompletion = now
+	p.lock.Unlock()
+	p.runs.Add(1)
+}
+
+// NewPeriodicTimeout schedules the specified TimerTask for repeated execution at a fixed rate.
+// The first run happens after initialDelay, and the following runs at initialDelay + n*period,
+// no matter how long each run takes. If the worker falls behind, or period is shorter than the tick
+// duration, the missed runs are skipped so that the schedule stays aligned to the original deadlines.
+// Cancel on the returned Timeout stops all future runs.
+func (tw *WheelTimer) NewPeriodicTimeout(task TimerTask, initialDelay, period time.Duration, opts ...TimeoutOption) (PeriodicTimeout, error) {
+	return tw.newPeriodicTimeout(task, initialDelay, period, false, opts)
+}
+
+// NewFixedDelayTimeout schedules the specified TimerTask for repeated execution with a fixed delay.
+// The first run happens after initialDelay, and each following run is scheduled delay after the previous
+// run has returned, so a slow task never overlaps itself. Cancelling the returned Timeout while a run is
+// in flight prevents the next run from being scheduled.
+func (tw *WheelTimer) NewFixedDelayTimeout(task TimerTask, initialDelay, delay time.Duration, opts ...TimeoutOption) (PeriodicTimeout, error) {
+	return tw.newPeriodicTimeout(task, initialDelay, delay, true, opts)
+}
+
+func (tw *WheelTimer) newPeriodicTimeout(task TimerTask, initialDelay, period time.Duration, fixedDelay bool, opts []TimeoutOption) (PeriodicTimeout, error) {
+	if period <= 0 {
+		return nil, fmt.Errorf("period: %d (expected: > 0)", period)
+	}
+
+	timeout := newWheelTimeout(tw, task, 0)
+	timeout.apply(opts)
+	timeout.periodic = &periodic{
+		period:     period,
+		fixedDelay: fixedDelay,
+	}
+	if err := tw.schedule(timeout, initialDelay); err != nil {
+		return nil, err
+	}
+	return timeout, nil
+}
+
+func (timeout *WheelTimeout) RunCount() int64 {
+	if timeout.periodic == nil {
+		return 0
+	}
+	return timeout.periodic.runs.Load()
+}
+
+func (timeout *WheelTimeout) LastError() error {
+	if timeout.periodic == nil {
+		return nil
+	}
+	timeout.periodic.lock.Lock()
+	defer timeout.periodic.lock.Unlock()
+	return timeout.periodic.lastErr
+}
+
+func (timeout *WheelTimeout) LastCompletion() time.Time {
+	if timeout.periodic == nil {
+		return time.Time{}
+	}
+	timeout.periodic.lock.Lock()
+	defer timeout.periodic.lock.Unlock()
+	return timeout.periodic.lastCompletion
+}
+
+// expirePeriodic hands a periodic timeout to the executor and puts it back into the wheel at its next deadline.
+// The timeout stays in the scheduled state, so that it can still be cancelled while it is running.
+func (timeout *WheelTimeout) expirePeriodic() {
+	if timeout.State() != TimeoutStateScheduled {
+		return
+	}
+
+	tw := timeout.timer
+	timeout.periodic.running.Add(1)
+	if timeout.periodic.schedule != nil {
+		timeout.expireSchedule()
+		return
+	}
+	if timeout.periodic.fixedDelay {
+		// the timeout is still pending while it is running, it is counted again when it has been removed
+		// from the bucket so that a concurrent Cancel can release it.
+		tw.pendingTimeouts.Add(1)
+		timeout.execute(timeout.runFixedDelay)
+		return
+	}
+
+	timeout.execute(timeout.run)
+	if tw.State() == workerStateDraining {
+		timeout.finishPeriodic(true)
+		return
+	}
+
+	period := timeout.periodic.period
+	next := timeout.getDeadline() + period
+	// the next run must be after the current tick, otherwise it would be fired again by the next tick.
+	if limit := tw.tickDuration * time.Duration(tw.tick+1); next < limit {
+		next += (limit - next + period - 1) / period * period
+	}
+	if next < timeout.getDeadline() {
+		next = math.MaxInt64
+	}
+	timeout.setDeadline(next)
+	tw.reschedule(timeout)
+}
+
+func (timeout *WheelTimeout) runFixedDelay(d dispatch) {
+	timeout.run(d)
+
+	if timeout.State() != TimeoutStateScheduled {
+		return
+	}
+
+	tw := timeout.timer
+	if tw.State() == workerStateDraining {
+		if timeout.finishPeriodic(false) {
+			tw.pendingTimeouts.Add(-1)
+		}
+		return
+	}
+	deadline := tw.elapsed() + timeout.periodic.period
+	if deadline < 0 {
+		deadline = math.MaxInt64
+	}
+	timeout.setDeadline(deadline)
+	if err := tw.requeue(timeout); err != nil {
+		// the timer has been stopped, which cancels the timeout as it would have done if it had been in the wheel.
+		tw.pendingTimeouts.Add(-1)
+		if timeout.state.CompareAndSwap(int32(TimeoutStateScheduled), int32(TimeoutStateCancelled)) {
+			timeout.complete(ErrCancelled)
+			timeout.cancelChildren(false)
+		}
+	}
+}
+
+// finishPeriodic expires a periodic timeout which will not be run again. It is completed here if no run is in
+// flight, or else by the last run. It reports whether the timeout has been finished by this call.
+func (timeout *WheelTimeout) finishPeriodic(onWorker bool) bool {
+	if !timeout.state.CompareAndSwap(int32(TimeoutStateScheduled), int32(TimeoutStateSucceeded)) {
+		return false
+	}
+	timeout.cancelChildren(onWorker)
+	if timeout.periodic.running.Load() == 0 {
+		timeout.complete(nil)
+	}
+	return true
+}
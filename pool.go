@@ -0,0 +1,215 @@
+package wheeltimer
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// RejectingExecutor is an Executor which may refuse a task. When the timer hands a task over to such an executor,
+// it uses ExecuteOrReject, and a rejected timeout completes with the error passed to reject instead of running.
+type RejectingExecutor interface {
+	Executor
+
+	// ExecuteOrReject is Runs task, or calls reject exactly once if task will never be run.
+	ExecuteOrReject(task func(), reject func(err error))
+}
+
+// RejectPolicy decides what a PoolExecutor does with a task when its queue is full.
+type RejectPolicy int
+
+const (
+	// RejectPolicyAbort rejects the new task with ErrRejected.
+	RejectPolicyAbort RejectPolicy = iota
+	// RejectPolicyCallerRuns runs the new task on the goroutine which submits it, which is the worker of the timer,
+	// so that the timer slows down instead of losing tasks. The wheel does not tick while the worker runs the task.
+	RejectPolicyCallerRuns
+	// RejectPolicyDropOldest rejects the oldest queued task with ErrRejected to make room for the new one.
+	RejectPolicyDropOldest
+	// RejectPolicyBlock waits until there is room in the queue. When the pool is the executor of a timer, the worker
+	// of the timer is the one waiting: the wheel stops ticking, and Stop, Shutdown and Close wait along with it until
+	// a queued task has been taken by a worker of the pool. It suits callers other than a timer, which should prefer
+	// RejectPolicyCallerRuns to slow down without stalling.
+	RejectPolicyBlock
+)
+
+// PoolStats is a snapshot of the metrics of a PoolExecutor.
+type PoolStats struct {
+	// Workers is the number of worker goroutines.
+	Workers int
+	// Busy is the number of workers which are running a task.
+	Busy int
+	// QueueDepth is the number of tasks waiting for a worker.
+	QueueDepth int
+	// QueueCapacity is the maximum number of tasks waiting for a worker.
+	QueueCapacity int
+	// Submitted is the number of tasks which have been handed to the executor.
+	Submitted int64
+	// Completed is the number of tasks which have been run, by a worker or by the caller.
+	Completed int64
+	// CallerRuns is the number of tasks which have been run by the caller because they could not be queued.
+	CallerRuns int64
+	// Rejected is the number of tasks which have been rejected, including the dropped ones.
+	Rejected int64
+}
+
+type poolTask struct {
+	run    func()
+	reject func(err error)
+}
+
+// PoolExecutor runs the tasks on a fixed number of reused worker goroutines, with a bounded queue in front of them.
+type PoolExecutor struct {
+	policy  RejectPolicy
+	workers int
+	queue   chan poolTask
+	lock    sync.RWMutex // held for reading while a task is queued, and for writing to close the queue
+	closed  bool
+	wg      sync.WaitGroup
+
+	busy       atomic.Int32
+	submitted  atomic.Int64
+	completed  atomic.Int64
+	callerRuns atomic.Int64
+	rejected   atomic.Int64
+}
+
+// NewPoolExecutor starts a PoolExecutor with the given number of workers, and a queue holding up to queueSize tasks.
+// The policy decides what happens to a task when the queue is full. Close stops the workers.
+func NewPoolExecutor(workers, queueSize int, policy RejectPolicy) (*PoolExecutor, error) {
+	if workers <= 0 {
+		return nil, fmt.Errorf("workers: %d (expected: > 0)", workers)
+	}
+	if queueSize < 0 {
+		return nil, fmt.Errorf("queueSize: %d (expected: >= 0)", queueSize)
+	}
+	if policy < RejectPolicyAbort || policy > RejectPolicyBlock {
+		return nil, fmt.Errorf("policy: %d (expected: a RejectPolicy)", policy)
+	}
+	if policy == RejectPolicyDropOldest && queueSize == 0 {
+		return nil, fmt.Errorf("queueSize: 0 (expected: > 0 with RejectPolicyDropOldest)")
+	}
+
+	p := &PoolExecutor{
+		policy:  policy,
+		workers: workers,
+		queue:   make(chan poolTask, queueSize),
+	}
+	p.wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go p.work()
+	}
+	return p, nil
+}
+
+func (p *PoolExecutor) work() {
+	defer p.wg.Done()
+	for task := range p.queue {
+		p.busy.Add(1)
+		task.run()
+		p.busy.Add(-1)
+		p.completed.Add(1)
+	}
+}
+
+// Execute runs task on a worker. Execute can not tell the caller that task has been rejected, so a task which the
+// policy rejects, or which is handed over after Close, is run by the caller instead, as with RejectPolicyCallerRuns.
+// Callers which can deal with a rejection must use ExecuteOrReject, as the timer does.
+func (p *PoolExecutor) Execute(task func()) {
+	p.ExecuteOrReject(task, nil)
+}
+
+// ExecuteOrReject runs task on a worker, or calls reject with ErrRejected if the policy rejects it. A nil reject
+// behaves like Execute.
+func (p *PoolExecutor) ExecuteOrReject(task func(), reject func(err error)) {
+	p.submitted.Add(1)
+	t := poolTask{run: task, reject: reject}
+
+	p.lock.RLock()
+	if p.closed {
+		p.lock.RUnlock()
+		p.reject(t)
+		return
+	}
+
+	switch p.policy {
+	case RejectPolicyBlock:
+		// the lock is held while waiting, so Close waits until the workers of the pool have made room.
+		p.queue <- t
+		p.lock.RUnlock()
+		return
+	case RejectPolicyDropOldest:
+		for {
+			select {
+			case p.queue <- t:
+				p.lock.RUnlock()
+				return
+			default:
+			}
+			select {
+			case oldest := <-p.queue:
+				p.reject(oldest)
+			default:
+			}
+		}
+	}
+
+	select {
+	case p.queue <- t:
+		p.lock.RUnlock()
+		return
+	default:
+	}
+	p.lock.RUnlock()
+
+	if p.policy == RejectPolicyCallerRuns {
+		p.runOnCaller(task)
+		return
+	}
+	p.reject(t)
+}
+
+// reject rejects t with ErrRejected. A task which has been handed over by Execute can not be rejected, it is run by
+// the caller instead.
+func (p *PoolExecutor) reject(t poolTask) {
+	if t.reject == nil {
+		p.runOnCaller(t.run)
+		return
+	}
+	p.rejected.Add(1)
+	t.reject(ErrRejected)
+}
+
+func (p *PoolExecutor) runOnCaller(task func()) {
+	p.callerRuns.Add(1)
+	task()
+	p.completed.Add(1)
+}
+
+// Stats is Returns the current metrics of the executor.
+func (p *PoolExecutor) Stats() PoolStats {
+	return PoolStats{
+		Workers:       p.workers,
+		Busy:          int(p.busy.Load()),
+		QueueDepth:    len(p.queue),
+		QueueCapacity: cap(p.queue),
+		Submitted:     p.submitted.Load(),
+		Completed:     p.completed.Load(),
+		CallerRuns:    p.callerRuns.Load(),
+		Rejected:      p.rejected.Load(),
+	}
+}
+
+// Close stops accepting tasks, and waits until the workers have run the queued tasks and exited. The tasks handed
+// to the executor after Close are rejected, or run by the caller if they are handed over by Execute.
+func (p *PoolExecutor) Close() {
+	p.lock.Lock()
+	if p.closed {
+		p.lock.Unlock()
+		return
+	}
+	p.closed = true
+	close(p.queue)
+	p.lock.Unlock()
+	p.wg.Wait()
+}
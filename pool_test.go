@@ -0,0 +1,239 @@
+package wheeltimer
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// blockPool occupies the only worker of pool until the returned function is called, the queue of pool must not be
+// full.
+func blockPool(pool *PoolExecutor) func() {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	pool.Execute(func() {
+		close(started)
+		<-release
+	})
+	<-started
+	return func() {
+		close(release)
+	}
+}
+
+func TestPoolExecutor(t *testing.T) {
+	pool, err := NewPoolExecutor(4, 16, RejectPolicyBlock)
+	assert.NoError(t, err)
+
+	var wg sync.WaitGroup
+	var ran atomic.Int32
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		pool.Execute(func() {
+			defer wg.Done()
+			ran.Add(1)
+		})
+	}
+	wg.Wait()
+	pool.Close()
+
+	assert.Equal(t, int32(100), ran.Load())
+	stats := pool.Stats()
+	assert.Equal(t, 4, stats.Workers)
+	assert.Equal(t, 16, stats.QueueCapacity)
+	assert.Equal(t, int64(100), stats.Submitted)
+	assert.Equal(t, int64(100), stats.Completed)
+	assert.Equal(t, int64(0), stats.Rejected)
+
+	var rejected error
+	pool.ExecuteOrReject(func() {}, func(err error) {
+		rejected = err
+	})
+	assert.ErrorIs(t, rejected, ErrRejected)
+
+	_, err = NewPoolExecutor(0, 1, RejectPolicyAbort)
+	assert.Error(t, err)
+	_, err = NewPoolExecutor(1, 0, RejectPolicyDropOldest)
+	assert.Error(t, err)
+}
+
+func TestPoolExecutor_RejectPolicy(t *testing.T) {
+	t.Run("abort", func(t *testing.T) {
+		pool, err := NewPoolExecutor(1, 1, RejectPolicyAbort)
+		assert.NoError(t, err)
+		release := blockPool(pool)
+
+		var rejected []int
+		for i := 0; i < 3; i++ {
+			i := i
+			pool.ExecuteOrReject(func() {}, func(err error) {
+				assert.ErrorIs(t, err, ErrRejected)
+				rejected = append(rejected, i)
+			})
+		}
+		assert.Equal(t, []int{1, 2}, rejected)
+		assert.Equal(t, 1, pool.Stats().QueueDepth)
+		assert.Equal(t, 1, pool.Stats().Busy)
+		release()
+		pool.Close()
+		assert.Equal(t, int64(2), pool.Stats().Rejected)
+	})
+
+	t.Run("execute", func(t *testing.T) {
+		pool, err := NewPoolExecutor(1, 1, RejectPolicyAbort)
+		assert.NoError(t, err)
+		release := blockPool(pool)
+		pool.Execute(func() {})
+
+		// a task handed over by Execute can not be rejected, it is run by the caller.
+		ran := false
+		pool.Execute(func() {
+			ran = true
+		})
+		assert.True(t, ran)
+		release()
+		pool.Close()
+
+		ran = false
+		pool.Execute(func() {
+			ran = true
+		})
+		assert.True(t, ran)
+		stats := pool.Stats()
+		assert.Equal(t, int64(2), stats.CallerRuns)
+		assert.Equal(t, int64(0), stats.Rejected)
+	})
+
+	t.Run("caller runs", func(t *testing.T) {
+		pool, err := NewPoolExecutor(1, 1, RejectPolicyCallerRuns)
+		assert.NoError(t, err)
+		release := blockPool(pool)
+		pool.Execute(func() {})
+
+		ran := false
+		pool.Execute(func() {
+			ran = true
+		})
+		assert.True(t, ran)
+		assert.Equal(t, int64(1), pool.Stats().CallerRuns)
+		release()
+		pool.Close()
+	})
+
+	t.Run("drop oldest", func(t *testing.T) {
+		pool, err := NewPoolExecutor(1, 2, RejectPolicyDropOldest)
+		assert.NoError(t, err)
+		release := blockPool(pool)
+
+		var lock sync.Mutex
+		var ran, rejected []int
+		for i := 0; i < 4; i++ {
+			i := i
+			pool.ExecuteOrReject(func() {
+				lock.Lock()
+				defer lock.Unlock()
+				ran = append(ran, i)
+			}, func(err error) {
+				rejected = append(rejected, i)
+			})
+		}
+		release()
+		pool.Close()
+		assert.Equal(t, []int{0, 1}, rejected)
+		assert.Equal(t, []int{2, 3}, ran)
+	})
+
+	t.Run("block", func(t *testing.T) {
+		pool, err := NewPoolExecutor(1, 1, RejectPolicyBlock)
+		assert.NoError(t, err)
+		release := blockPool(pool)
+
+		pool.Execute(func() {})
+		submitted := make(chan struct{})
+		go func() {
+			pool.Execute(func() {})
+			close(submitted)
+		}()
+		select {
+		case <-submitted:
+			t.Fatal("the task has been queued while the queue is full")
+		case <-time.After(time.Millisecond * 20):
+		}
+		release()
+		<-submitted
+		pool.Close()
+		assert.Equal(t, int64(3), pool.Stats().Completed)
+	})
+}
+
+func TestPoolExecutor_Timer(t *testing.T) {
+	pool, err := NewPoolExecutor(1, 1, RejectPolicyAbort)
+	assert.NoError(t, err)
+	defer pool.Close()
+	wheel, advance := newFakeClockWheel(t, time.Millisecond*10, WithExecutor(pool))
+	defer wheel.Stop()
+
+	release := blockPool(pool)
+	defer release()
+	pool.Execute(func() {})
+	timeout, err := wheel.NewTimeout(TimerTaskFunc(func(timeout Timeout) error {
+		return nil
+	}), time.Millisecond*10)
+	assert.NoError(t, err)
+
+	advance(time.Millisecond * 20)
+	<-timeout.Done()
+	assert.ErrorIs(t, timeout.Err(), ErrRejected)
+	assert.Equal(t, TimeoutStateFailed, timeout.State())
+	assert.Equal(t, 1, timeout.Attempt())
+}
+
+func TestPoolExecutor_CallerRunsOnTimer(t *testing.T) {
+	pool, err := NewPoolExecutor(1, 1, RejectPolicyCallerRuns)
+	assert.NoError(t, err)
+	defer pool.Close()
+	wheel, advance := newFakeClockWheel(t, time.Millisecond*10, WithExecutor(pool), WithRingBufferSize(8))
+	defer wheel.Stop()
+	release := blockPool(pool)
+	defer release()
+	pool.Execute(func() {})
+
+	// the worker of the timer runs more fixed-delay timeouts in the same tick than the ring buffer holds, and puts
+	// them back itself.
+	var runs atomic.Int32
+	var timeouts []PeriodicTimeout
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 4; j++ {
+			timeout, err := wheel.NewFixedDelayTimeout(TimerTaskFunc(func(timeout Timeout) error {
+				runs.Add(1)
+				return nil
+			}), time.Millisecond*time.Duration(30-i*10), time.Millisecond*10)
+			assert.NoError(t, err)
+			timeouts = append(timeouts, timeout)
+		}
+		advance(time.Millisecond * 10)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		advance(time.Millisecond * 40)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second * 5):
+		assert.FailNow(t, "the worker is stuck on the full ring buffer")
+	}
+	assert.GreaterOrEqual(t, runs.Load(), int32(2*len(timeouts)))
+	assert.Equal(t, int64(runs.Load()), pool.Stats().CallerRuns)
+	for i, timeout := range timeouts {
+		assert.True(t, timeout.Cancel())
+		if i%4 == 3 {
+			advance(time.Millisecond * 10)
+		}
+	}
+	assert.Equal(t, int64(0), wheel.PendingTimeouts())
+}
@@ -0,0 +1,111 @@
+package wheeltimer
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"time"
+)
+
+const (
+	// DefaultRetryBackoff is the delay before the first retry when RetryPolicy.InitialBackoff is not set.
+	DefaultRetryBackoff = time.Second
+	// DefaultRetryMultiplier is the growth factor of the backoff when RetryPolicy.Multiplier is not set.
+	DefaultRetryMultiplier = 2.0
+)
+
+// RetryPolicy describes how a one-shot timeout is retried when its TimerTask returns an error or panics.
+// The retry is scheduled on the same timer, and the task can tell the attempt it is running by Timeout.Attempt.
+// Periodic timeouts are never retried, their next run takes the place of a retry.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of runs of the task, including the first one. A value below 2 disables retries.
+	MaxAttempts int
+	// InitialBackoff is the delay between the first failed run and the first retry, DefaultRetryBackoff if zero.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the delay between two runs, or is ignored if zero.
+	MaxBackoff time.Duration
+	// Multiplier is the factor by which the backoff grows after every retry, DefaultRetryMultiplier if zero.
+	Multiplier float64
+	// Jitter randomizes every backoff by up to this fraction of it in either direction, it must be in [0, 1].
+	Jitter float64
+	// Retryable reports whether a run which failed with err should be retried. If nil, every error is retried,
+	// including a *PanicError.
+	Retryable func(err error) bool
+}
+
+// shouldRetry reports whether a run which has been the given attempt and failed with err should be retried.
+func (p *RetryPolicy) shouldRetry(attempt int, err error) bool {
+	if p == nil || err == nil || attempt >= p.MaxAttempts {
+		return false
+	}
+	return p.Retryable == nil || p.Retryable(err)
+}
+
+// backoff is Returns the delay before the retry which follows the given failed attempt.
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	initial := p.InitialBackoff
+	if initial <= 0 {
+		initial = DefaultRetryBackoff
+	}
+	multiplier := p.Multiplier
+	if multiplier <= 0 {
+		multiplier = DefaultRetryMultiplier
+	}
+
+	backoff := float64(initial) * math.Pow(multiplier, float64(attempt-1))
+	if p.Jitter > 0 {
+		backoff += backoff * p.Jitter * (2*rand.Float64() - 1)
+	}
+	if p.MaxBackoff > 0 && backoff > float64(p.MaxBackoff) {
+		backoff = float64(p.MaxBackoff)
+	}
+	if backoff >= math.MaxInt64 {
+		return math.MaxInt64
+	}
+	if backoff < 0 {
+		return 0
+	}
+	return time.Duration(backoff)
+}
+
+// retry schedules the next attempt of a one-shot timeout whose task has failed with err. It reports whether the
+// timeout has been put back into the wheel, in which case it must not be completed.
+func (timeout *WheelTimeout) retry(err error) bool {
+	attempt := int(timeout.attempt.Load())
+	if !timeout.retryPolicy.shouldRetry(attempt, err) {
+		return false
+	}
+	// a timeout whose context is done would be cancelled right away, and a draining timer runs no new attempts.
+	if timeout.ctx != nil && timeout.ctx.ctx.Err() != nil {
+		return false
+	}
+	tw := timeout.timer
+	if tw.State() != workerStateStarted {
+		return false
+	}
+
+	deadline := tw.elapsed() + timeout.retryPolicy.backoff(attempt)
+	if deadline < 0 {
+		deadline = math.MaxInt64
+	}
+	timeout.setDeadline(deadline)
+	// the retry is scheduled by delay, even if the first attempt has been scheduled for a wall clock instant.
+	timeout.wallDeadline = 0
+
+	tw.pendingTimeouts.Add(1)
+	timeout.state.Store(int32(TimeoutStateScheduled))
+	if timeout.ctx != nil {
+		// the context has been released when the timeout expired, it cancels the retry as well.
+		stop := context.AfterFunc(timeout.ctx.ctx, func() {
+			timeout.Cancel()
+		})
+		timeout.ctx.stop.Store(&stop)
+	}
+	if err := tw.requeue(timeout); err != nil {
+		// the timer has been stopped in the meantime, the failure of the last attempt is final.
+		tw.pendingTimeouts.Add(-1)
+		timeout.releaseContext()
+		return !timeout.state.CompareAndSwap(int32(TimeoutStateScheduled), int32(TimeoutStateRunning))
+	}
+	return true
+}
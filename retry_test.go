@@ -0,0 +1,118 @@
+package wheeltimer
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	policy := &RetryPolicy{InitialBackoff: time.Millisecond * 10, MaxBackoff: time.Millisecond * 50}
+	assert.Equal(t, time.Millisecond*10, policy.backoff(1))
+	assert.Equal(t, time.Millisecond*20, policy.backoff(2))
+	assert.Equal(t, time.Millisecond*40, policy.backoff(3))
+	assert.Equal(t, time.Millisecond*50, policy.backoff(4))
+	assert.Equal(t, time.Millisecond*50, policy.backoff(100))
+
+	policy = &RetryPolicy{InitialBackoff: time.Millisecond * 100, Multiplier: 1, Jitter: 0.5}
+	for i := 0; i < 100; i++ {
+		backoff := policy.backoff(1)
+		assert.GreaterOrEqual(t, backoff, time.Millisecond*50)
+		assert.LessOrEqual(t, backoff, time.Millisecond*150)
+	}
+
+	assert.Equal(t, DefaultRetryBackoff, (&RetryPolicy{}).backoff(1))
+}
+
+func TestRetryPolicy_ShouldRetry(t *testing.T) {
+	errRetryable := errors.New("retryable")
+	policy := &RetryPolicy{
+		MaxAttempts: 3,
+		Retryable: func(err error) bool {
+			return errors.Is(err, errRetryable)
+		},
+	}
+	assert.True(t, policy.shouldRetry(1, errRetryable))
+	assert.True(t, policy.shouldRetry(2, errRetryable))
+	assert.False(t, policy.shouldRetry(3, errRetryable))
+	assert.False(t, policy.shouldRetry(1, errors.New("other")))
+	assert.False(t, policy.shouldRetry(1, nil))
+
+	var disabled *RetryPolicy
+	assert.False(t, disabled.shouldRetry(1, errRetryable))
+}
+
+func TestTimeoutRetry(t *testing.T) {
+	policy := &RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond * 20}
+	wheel, advance := newFakeClockWheel(t, time.Millisecond*10, WithRetryPolicy(policy))
+	defer wheel.Stop()
+
+	errFailed := errors.New("failed")
+
+	t.Run("succeeds", func(t *testing.T) {
+		var attempts []int
+		timeout, err := wheel.NewTimeout(TimerTaskFunc(func(timeout Timeout) error {
+			attempts = append(attempts, timeout.Attempt())
+			if timeout.Attempt() < 3 {
+				return errFailed
+			}
+			return nil
+		}), time.Millisecond*10)
+		assert.NoError(t, err)
+
+		advance(time.Millisecond * 20)
+		assert.Equal(t, []int{1}, attempts)
+		assert.Equal(t, TimeoutStateScheduled, timeout.State())
+		assert.Equal(t, int64(1), wheel.PendingTimeouts())
+
+		// the backoff doubles after every retry.
+		advance(time.Millisecond * 40)
+		assert.Equal(t, []int{1, 2}, attempts)
+		advance(time.Millisecond * 20)
+		assert.Equal(t, []int{1, 2}, attempts)
+		advance(time.Millisecond * 40)
+		assert.Equal(t, []int{1, 2, 3}, attempts)
+		assert.Equal(t, TimeoutStateSucceeded, timeout.State())
+		assert.NoError(t, timeout.Err())
+		assert.Equal(t, int64(0), wheel.PendingTimeouts())
+	})
+
+	t.Run("gives up", func(t *testing.T) {
+		timeout, err := wheel.NewTimeout(TimerTaskFunc(func(timeout Timeout) error {
+			return errFailed
+		}), time.Millisecond*10)
+		assert.NoError(t, err)
+
+		advance(time.Millisecond * 200)
+		assert.Equal(t, 3, timeout.Attempt())
+		assert.Equal(t, TimeoutStateFailed, timeout.State())
+		assert.ErrorIs(t, timeout.Err(), errFailed)
+	})
+
+	t.Run("per timeout policy", func(t *testing.T) {
+		timeout, err := wheel.NewTimeoutWithOptions(TimerTaskFunc(func(timeout Timeout) error {
+			return errFailed
+		}), time.Millisecond*10, WithTimeoutRetryPolicy(nil))
+		assert.NoError(t, err)
+
+		advance(time.Millisecond * 200)
+		assert.Equal(t, 1, timeout.Attempt())
+		assert.Equal(t, TimeoutStateFailed, timeout.State())
+	})
+
+	t.Run("cancel between attempts", func(t *testing.T) {
+		timeout, err := wheel.NewTimeout(TimerTaskFunc(func(timeout Timeout) error {
+			return errFailed
+		}), time.Millisecond*10)
+		assert.NoError(t, err)
+
+		advance(time.Millisecond * 20)
+		assert.True(t, timeout.Cancel())
+		advance(time.Millisecond * 100)
+		assert.Equal(t, 1, timeout.Attempt())
+		assert.ErrorIs(t, timeout.Err(), ErrCancelled)
+		assert.Equal(t, int64(0), wheel.PendingTimeouts())
+	})
+}
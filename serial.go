@@ -0,0 +1,136 @@
+package wheeltimer
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"sync/atomic"
+)
+
+// TimeoutExecutor is an Executor which is told the timeout a task belongs to, e.g. to route it by Timeout.Key.
+// When the executor of a timer implements it, the timer uses ExecuteTimeout instead of Execute.
+type TimeoutExecutor interface {
+	Executor
+
+	// ExecuteTimeout is Runs task, which runs the TimerTask of timeout.
+	ExecuteTimeout(timeout Timeout, task func())
+}
+
+// WithKey sets the key of the timeout, which a TimeoutExecutor such as SerialExecutor can use to run the tasks
+// sharing a key one at a time. NewKeyedTimeout sets the key it registers the timeout under.
+func WithKey(key string) TimeoutOption {
+	return func(timeout *WheelTimeout) {
+		timeout.key = key
+	}
+}
+
+// SerialExecutor runs the tasks on a fixed number of lanes, each of which is a goroutine running its tasks one at a
+// time in the order they have been handed over. The tasks of the timeouts which share a key are hashed onto the
+// same lane, so they never overlap and run in the order the timer has fired them, which is the order of their
+// deadlines up to the tick duration. Tasks without a key are spread over the lanes.
+//
+// The queue of a lane is unbounded, so the worker of the timer is never blocked by a slow task.
+type SerialExecutor struct {
+	lanes []*serialLane
+	next  atomic.Uint32
+	wg    sync.WaitGroup
+}
+
+type serialLane struct {
+	lock   sync.Mutex
+	cond   *sync.Cond
+	queue  []func()
+	closed bool
+}
+
+// NewSerialExecutor starts a SerialExecutor with the given number of lanes. Close stops them.
+func NewSerialExecutor(lanes int) (*SerialExecutor, error) {
+	if lanes <= 0 {
+		return nil, fmt.Errorf("lanes: %d (expected: > 0)", lanes)
+	}
+
+	e := &SerialExecutor{
+		lanes: make([]*serialLane, lanes),
+	}
+	e.wg.Add(lanes)
+	for i := range e.lanes {
+		lane := &serialLane{}
+		lane.cond = sync.NewCond(&lane.lock)
+		e.lanes[i] = lane
+		go func() {
+			defer e.wg.Done()
+			lane.work()
+		}()
+	}
+	return e, nil
+}
+
+func (l *serialLane) work() {
+	for {
+		l.lock.Lock()
+		for len(l.queue) == 0 && !l.closed {
+			l.cond.Wait()
+		}
+		if len(l.queue) == 0 {
+			l.lock.Unlock()
+			return
+		}
+		task := l.queue[0]
+		l.queue[0] = nil
+		l.queue = l.queue[1:]
+		l.lock.Unlock()
+
+		task()
+	}
+}
+
+// push queues task, it reports false if the lane has been closed.
+func (l *serialLane) push(task func()) bool {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.closed {
+		return false
+	}
+	l.queue = append(l.queue, task)
+	l.cond.Signal()
+	return true
+}
+
+// Execute runs a task without a key on the next lane.
+func (e *SerialExecutor) Execute(task func()) {
+	e.run(e.lanes[int(e.next.Add(1)-1)%len(e.lanes)], task)
+}
+
+func (e *SerialExecutor) ExecuteTimeout(timeout Timeout, task func()) {
+	key := timeout.Key()
+	if key == "" {
+		e.Execute(task)
+		return
+	}
+	e.run(e.lanes[e.lane(key)], task)
+}
+
+func (e *SerialExecutor) lane(key string) int {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	return int(h.Sum32() % uint32(len(e.lanes)))
+}
+
+func (e *SerialExecutor) run(lane *serialLane, task func()) {
+	if !lane.push(task) {
+		// like the default executor, a closed executor still runs the task rather than losing it.
+		go task()
+	}
+}
+
+// Close waits until the lanes have run the queued tasks and stops them. The tasks handed over afterwards are run on
+// their own goroutines.
+func (e *SerialExecutor) Close() {
+	for _, lane := range e.lanes {
+		lane.lock.Lock()
+		lane.closed = true
+		lane.cond.Signal()
+		lane.lock.Unlock()
+	}
+	e.wg.Wait()
+}
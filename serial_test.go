@@ -0,0 +1,84 @@
+package wheeltimer
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSerialExecutor(t *testing.T) {
+	executor, err := NewSerialExecutor(4)
+	assert.NoError(t, err)
+	wheel, advance := newFakeClockWheel(t, time.Millisecond*10, WithExecutor(executor))
+
+	var lock sync.Mutex
+	order := make(map[string][]int)
+	running := make(map[string]*atomic.Int32)
+	var overlaps atomic.Int32
+	var wg sync.WaitGroup
+	for _, key := range []string{"a", "b", "c"} {
+		running[key] = &atomic.Int32{}
+		for i := 0; i < 10; i++ {
+			key, i := key, i
+			wg.Add(1)
+			_, err := wheel.NewTimeoutWithOptions(TimerTaskFunc(func(timeout Timeout) error {
+				defer wg.Done()
+				assert.Equal(t, key, timeout.Key())
+				if running[key].Add(1) > 1 {
+					overlaps.Add(1)
+				}
+				time.Sleep(time.Millisecond)
+				running[key].Add(-1)
+
+				lock.Lock()
+				defer lock.Unlock()
+				order[key] = append(order[key], i)
+				return nil
+			}), time.Duration(10-i)*time.Millisecond*10, WithKey(key))
+			assert.NoError(t, err)
+		}
+	}
+
+	advance(time.Millisecond * 110)
+	wg.Wait()
+	wheel.Stop()
+	executor.Close()
+
+	assert.Equal(t, int32(0), overlaps.Load())
+	for key, runs := range order {
+		assert.Equal(t, []int{9, 8, 7, 6, 5, 4, 3, 2, 1, 0}, runs, "key %s", key)
+	}
+}
+
+func TestSerialExecutor_Lanes(t *testing.T) {
+	executor, err := NewSerialExecutor(8)
+	assert.NoError(t, err)
+	defer executor.Close()
+
+	lanes := make(map[int]bool)
+	for i := 0; i < 100; i++ {
+		lane := executor.lane(fmt.Sprintf("user-%d", i))
+		assert.Equal(t, lane, executor.lane(fmt.Sprintf("user-%d", i)))
+		lanes[lane] = true
+	}
+	assert.Len(t, lanes, 8)
+
+	var wg sync.WaitGroup
+	var ran atomic.Int32
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		executor.Execute(func() {
+			defer wg.Done()
+			ran.Add(1)
+		})
+	}
+	wg.Wait()
+	assert.Equal(t, int32(100), ran.Load())
+
+	_, err = NewSerialExecutor(0)
+	assert.Error(t, err)
+}
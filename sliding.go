@@ -0,0 +1,63 @@
+package wheeltimer
+
+import (
+	"fmt"
+	"math"
+	"sync/atomic"
+	"time"
+)
+
+// SlidingTimeout is the handle of a TimerTask which is run once the timeout has not been touched for a while.
+type SlidingTimeout interface {
+	Timeout
+
+	// Touch is Extends the deadline to the idle duration from now. It only records the time, the worker checks
+	// it when the timeout reaches its deadline and moves the timeout further if it has been touched since.
+	Touch()
+}
+
+type sliding struct {
+	idle      time.Duration
+	lastTouch atomic.Int64 // the time elapsed on the timer at the last call to Touch
+}
+
+// NewSlidingTimeout schedules the specified TimerTask for one-time execution once the returned timeout has not been
+// touched for the idle duration, like the idle detection of a connection. Touch does not allocate or wake up the
+// worker, so it can be called on every read or write.
+func (tw *WheelTimer) NewSlidingTimeout(task TimerTask, idle time.Duration, opts ...TimeoutOption) (SlidingTimeout, error) {
+	if idle <= 0 {
+		return nil, fmt.Errorf("idle: %d (expected: > 0)", idle)
+	}
+
+	timeout := newWheelTimeout(tw, task, 0)
+	timeout.apply(opts)
+	timeout.sliding = &sliding{idle: idle}
+	if err := tw.schedule(timeout, idle); err != nil {
+		return nil, err
+	}
+	return timeout, nil
+}
+
+func (timeout *WheelTimeout) Touch() {
+	if timeout.sliding == nil {
+		return
+	}
+	timeout.sliding.lastTouch.Store(int64(timeout.timer.elapsed()))
+}
+
+// slide puts a sliding timeout which has been touched after its deadline was set back into the wheel at the new
+// deadline. It reports whether the timeout has been moved.
+func (timeout *WheelTimeout) slide() bool {
+	tw := timeout.timer
+	deadline := time.Duration(timeout.sliding.lastTouch.Load()) + timeout.sliding.idle
+	if deadline < 0 {
+		deadline = math.MaxInt64
+	}
+	// the timeout would be fired again by the next tick if the new deadline is not after the current tick.
+	if deadline <= tw.tickDuration*time.Duration(tw.tick+1) {
+		return false
+	}
+	timeout.setDeadline(deadline)
+	tw.reschedule(timeout)
+	return true
+}
@@ -0,0 +1,56 @@
+package wheeltimer
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSlidingTimeout(t *testing.T) {
+	wheel, advance := newFakeClockWheel(t, time.Millisecond*10)
+	defer wheel.Stop()
+
+	var fired atomic.Int32
+	timeout, err := wheel.NewSlidingTimeout(TimerTaskFunc(func(timeout Timeout) error {
+		fired.Add(1)
+		return nil
+	}), time.Millisecond*50)
+	assert.NoError(t, err)
+
+	// an active timeout is moved along each time it reaches its deadline.
+	for i := 0; i < 10; i++ {
+		advance(time.Millisecond * 20)
+		timeout.Touch()
+	}
+	assert.Equal(t, int32(0), fired.Load())
+	assert.Equal(t, int64(1), wheel.PendingTimeouts())
+
+	advance(time.Millisecond * 40)
+	assert.Equal(t, int32(0), fired.Load())
+	advance(time.Millisecond * 30)
+	assert.Equal(t, int32(1), fired.Load())
+	assert.Equal(t, TimeoutStateSucceeded, timeout.State())
+	assert.Equal(t, int64(0), wheel.PendingTimeouts())
+
+	t.Run("cancel", func(t *testing.T) {
+		timeout, err := wheel.NewSlidingTimeout(TimerTaskFunc(func(timeout Timeout) error {
+			fired.Add(1)
+			return nil
+		}), time.Millisecond*30)
+		assert.NoError(t, err)
+		advance(time.Millisecond * 20)
+		timeout.Touch()
+		advance(time.Millisecond * 20)
+		assert.True(t, timeout.Cancel())
+		advance(time.Millisecond * 50)
+		assert.Equal(t, int32(1), fired.Load())
+		assert.Equal(t, int64(0), wheel.PendingTimeouts())
+	})
+
+	_, err = wheel.NewSlidingTimeout(TimerTaskFunc(func(timeout Timeout) error {
+		return nil
+	}), 0)
+	assert.Error(t, err)
+}
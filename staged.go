@@ -0,0 +1,175 @@
+package wheeltimer
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"runtime/debug"
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+// Stage is a step of a staged timeout. It fires at Fraction of the deadline of the timeout plus Offset,
+// e.g. a warning at 80% of the deadline is Stage{Fraction: 0.8}, and a hard kill a second after the deadline is
+// Stage{Fraction: 1, Offset: time.Second}.
+type Stage struct {
+	Fraction float64
+	Offset   time.Duration
+	Task     TimerTask
+}
+
+// StagedTimeout is the handle of a sequence of TimerTasks scheduled at the stages of a single deadline.
+type StagedTimeout interface {
+	Timeout
+
+	// Stage is Returns the number of stages which have fired since the sequence has been started or reset.
+	Stage() int
+}
+
+type staged struct {
+	stages []Stage
+	start  time.Duration   // the time elapsed on the timer when the current sequence started
+	at     []time.Duration // the fire times of the stages relative to start
+	order  []int           // the indexes of the stages, in the order in which they fire
+	next   int             // the position in order of the next stage to fire
+	final  TimerTask       // the task of the last stage, once it has fired
+
+	fired      atomic.Int32
+	resetDelay atomic.Int64          // the deadline of the sequence requested by the last call to Reset
+	last       atomic.Pointer[Stage] // the stage which fires last in the current order, see Task
+}
+
+// restart computes the fire times of the stages for a sequence which starts at start and has the given deadline.
+func (s *staged) restart(start, deadline time.Duration) {
+	s.start = start
+	for i, stage := range s.stages {
+		at := time.Duration(stage.Fraction*float64(deadline)) + stage.Offset
+		if at < 0 {
+			at = 0
+		}
+		s.at[i] = at
+		s.order[i] = i
+	}
+	sort.SliceStable(s.order, func(i, j int) bool {
+		return s.at[s.order[i]] < s.at[s.order[j]]
+	})
+	s.last.Store(&s.stages[s.order[len(s.order)-1]])
+	s.next = 0
+	s.fired.Store(0)
+}
+
+// nextDeadline is Returns the deadline of the next stage on the clock of the timer.
+func (s *staged) nextDeadline() time.Duration {
+	deadline := s.start + s.at[s.order[s.next]]
+	if deadline < s.start {
+		return math.MaxInt64
+	}
+	return deadline
+}
+
+// NewStagedTimeout schedules the tasks of stages at their fractions or offsets of deadline, and tracks them as a
+// single timeout. The stages fire in the order of their fire times. The timeout expires when the last stage fires,
+// so Done, Err, State and the retry policy follow the task of the last stage, while the tasks of the earlier stages
+// are only logged and reported to the dead letter sink if they panic. Task is Returns the task of the stage which
+// fires last in the current sequence, which may change when Reset reorders the stages.
+//
+// Cancel cancels the stages which have not fired yet. Reset(d) starts the sequence over with a deadline of d.
+func (tw *WheelTimer) NewStagedTimeout(deadline time.Duration, stages []Stage, opts ...TimeoutOption) (StagedTimeout, error) {
+	if deadline < 0 {
+		return nil, fmt.Errorf("deadline: %d (expected: >= 0)", deadline)
+	}
+	if len(stages) == 0 {
+		return nil, errors.New("stages: empty (expected: at least one stage)")
+	}
+	for i, stage := range stages {
+		if stage.Task == nil {
+			return nil, fmt.Errorf("stages[%d]: nil task", i)
+		}
+		if stage.Fraction < 0 {
+			return nil, fmt.Errorf("stages[%d]: fraction %f (expected: >= 0)", i, stage.Fraction)
+		}
+	}
+
+	s := &staged{
+		stages: append([]Stage(nil), stages...),
+		at:     make([]time.Duration, len(stages)),
+		order:  make([]int, len(stages)),
+	}
+	s.restart(0, deadline)
+	timeout := newWheelTimeout(tw, stages[s.order[len(stages)-1]].Task, 0)
+	timeout.apply(opts)
+	timeout.staged = s
+	if err := tw.schedule(timeout, s.at[s.order[0]]); err != nil {
+		return nil, err
+	}
+	return timeout, nil
+}
+
+func (timeout *WheelTimeout) Stage() int {
+	if timeout.staged == nil {
+		return 0
+	}
+	return int(timeout.staged.fired.Load())
+}
+
+// expireStage fires a stage of a staged timeout, and puts the timeout back into the wheel at the next stage.
+// The last stage expires the timeout like a one-shot timeout. It reports whether the timeout has been handled.
+func (timeout *WheelTimeout) expireStage() bool {
+	s := timeout.staged
+	if s.next >= len(s.order)-1 {
+		if s.next == len(s.order)-1 {
+			// a retry of the last stage keeps its task.
+			s.final = s.stages[s.order[s.next]].Task
+			s.next++
+			s.fired.Add(1)
+		}
+		return false
+	}
+	if timeout.State() != TimeoutStateScheduled {
+		return true
+	}
+
+	if s.next == 0 {
+		// the sequence started when the first stage was scheduled, which is only known to the worker.
+		s.start = timeout.getDeadline() - s.at[s.order[0]]
+	}
+	task := s.stages[s.order[s.next]].Task
+	s.next++
+	s.fired.Add(1)
+	timeout.execute(func(d dispatch) {
+		timeout.runStage(task, d)
+	})
+
+	timeout.setDeadline(s.nextDeadline())
+	timeout.timer.reschedule(timeout)
+	return true
+}
+
+// runStage runs the task of a stage which is not the last one.
+func (timeout *WheelTimeout) runStage(task TimerTask, d dispatch) {
+	defer func() {
+		if r := recover(); r != nil {
+			timeout.timer.panicHandler(r)
+			timeout.deadLetter(&PanicError{Value: r, Stack: debug.Stack()})
+		}
+	}()
+
+	runAt := timeout.timer.clock.Now()
+	if d.rejected != nil {
+		timeout.handleError(d.rejected, d, runAt)
+		return
+	}
+	if err := timeout.runTask(task); err != nil {
+		timeout.handleError(err, d, runAt)
+	}
+}
+
+// resetStages starts the sequence of a staged timeout over, with the deadline requested by the last call to Reset.
+// It must only be called from the worker goroutine.
+func (timeout *WheelTimeout) resetStages(deadline time.Duration) {
+	s := timeout.staged
+	delay := time.Duration(s.resetDelay.Load())
+	s.restart(deadline-delay, delay)
+	timeout.setDeadline(s.nextDeadline())
+}
@@ -0,0 +1,123 @@
+package wheeltimer
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStagedTimeout(t *testing.T) {
+	wheel, advance := newFakeClockWheel(t, time.Millisecond*10)
+	defer wheel.Stop()
+
+	var lock sync.Mutex
+	var fired []string
+	task := func(name string) TimerTask {
+		return TimerTaskFunc(func(timeout Timeout) error {
+			lock.Lock()
+			defer lock.Unlock()
+			fired = append(fired, name)
+			return nil
+		})
+	}
+	stages := []Stage{
+		{Fraction: 1.2, Task: task("kill")},
+		{Fraction: 0.8, Task: task("warn")},
+		{Fraction: 1, Task: task("abort")},
+	}
+
+	t.Run("fire", func(t *testing.T) {
+		fired = nil
+		timeout, err := wheel.NewStagedTimeout(time.Millisecond*100, stages)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, int(wheel.PendingTimeouts()))
+
+		advance(time.Millisecond * 90)
+		assert.Equal(t, []string{"warn"}, fired)
+		assert.Equal(t, 1, timeout.Stage())
+		assert.Equal(t, TimeoutStateScheduled, timeout.State())
+
+		advance(time.Millisecond * 20)
+		assert.Equal(t, []string{"warn", "abort"}, fired)
+		advance(time.Millisecond * 20)
+		assert.Equal(t, []string{"warn", "abort", "kill"}, fired)
+		assert.Equal(t, 3, timeout.Stage())
+		assert.Equal(t, TimeoutStateSucceeded, timeout.State())
+		assert.Equal(t, int64(0), wheel.PendingTimeouts())
+	})
+
+	t.Run("cancel", func(t *testing.T) {
+		fired = nil
+		timeout, err := wheel.NewStagedTimeout(time.Millisecond*100, stages)
+		assert.NoError(t, err)
+
+		advance(time.Millisecond * 90)
+		assert.True(t, timeout.Cancel())
+		advance(time.Millisecond * 100)
+		assert.Equal(t, []string{"warn"}, fired)
+		assert.ErrorIs(t, timeout.Err(), ErrCancelled)
+		assert.Equal(t, int64(0), wheel.PendingTimeouts())
+	})
+
+	t.Run("reset", func(t *testing.T) {
+		fired = nil
+		timeout, err := wheel.NewStagedTimeout(time.Millisecond*100, stages)
+		assert.NoError(t, err)
+
+		advance(time.Millisecond * 90)
+		assert.Equal(t, []string{"warn"}, fired)
+		assert.True(t, timeout.Reset(time.Millisecond*50))
+		advance(time.Millisecond * 10)
+		assert.Equal(t, 0, timeout.Stage())
+
+		// the sequence starts over with the new deadline.
+		advance(time.Millisecond * 40)
+		assert.Equal(t, []string{"warn", "warn"}, fired)
+		advance(time.Millisecond * 30)
+		assert.Equal(t, []string{"warn", "warn", "abort", "kill"}, fired)
+		assert.True(t, timeout.IsExpired())
+		assert.False(t, timeout.Reset(time.Millisecond*50))
+	})
+
+	t.Run("reset reorders", func(t *testing.T) {
+		kill := &stageTask{}
+		grace := &stageTask{}
+		timeout, err := wheel.NewStagedTimeout(time.Millisecond*100, []Stage{
+			{Fraction: 1, Task: kill},
+			{Offset: time.Millisecond * 50, Task: grace},
+		})
+		assert.NoError(t, err)
+		assert.Same(t, kill, timeout.Task())
+
+		// with a shorter deadline, the stage at a fixed offset fires last.
+		assert.True(t, timeout.Reset(time.Millisecond*20))
+		advance(time.Millisecond * 10)
+		assert.Same(t, grace, timeout.Task())
+		advance(time.Millisecond * 60)
+		assert.Equal(t, int32(1), kill.runs.Load())
+		assert.Equal(t, int32(1), grace.runs.Load())
+		assert.Same(t, grace, timeout.Task())
+		assert.Equal(t, TimeoutStateSucceeded, timeout.State())
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := wheel.NewStagedTimeout(time.Millisecond*100, nil)
+		assert.Error(t, err)
+		_, err = wheel.NewStagedTimeout(time.Millisecond*100, []Stage{{Fraction: 1}})
+		assert.Error(t, err)
+		_, err = wheel.NewStagedTimeout(time.Millisecond*100, []Stage{{Fraction: -1, Task: task("warn")}})
+		assert.Error(t, err)
+	})
+}
+
+type stageTask struct {
+	runs atomic.Int32
+}
+
+func (s *stageTask) Run(timeout Timeout) error {
+	s.runs.Add(1)
+	return nil
+}
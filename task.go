@@ -1,12 +1,24 @@
 package wheeltimer
 
-import "time"
+import (
+	"context"
+	"time"
+)
 
 type TimerTask interface {
 	// Run is Executed after the delay specified with newTimeout.
 	Run(timeout Timeout) error
 }
 
+// ContextTimerTask is a TimerTask which is run with a context. The context is cancelled when the context
+// the timeout has been created with is done, or when the timer is stopped.
+type ContextTimerTask interface {
+	TimerTask
+
+	// RunContext is Executed instead of Run after the delay specified with newTimeout.
+	RunContext(ctx context.Context, timeout Timeout) error
+}
+
 type Timer interface {
 	// NewTimeout is Schedules the specified TimerTask for one-time execution after the specified delay.
 	NewTimeout(task TimerTask, delay time.Duration) (Timeout, error)
@@ -23,15 +35,43 @@ type Timeout interface {
 	// Task is Returns the TimerTask which is associated with this handle.
 	Task() TimerTask
 
-	// IsExpired is Returns true if and only if the TimerTask associated with this handle has been expired.
+	// IsExpired is Returns true if and only if the TimerTask associated with this handle has been expired, that is
+	// it has fired and been handed to the executor. Use State to tell whether it has finished running.
 	IsExpired() bool
 
 	// IsCancelled is Returns true if and only if the TimerTask associated with this handle has been cancelled.
 	IsCancelled() bool
 
+	// State is Returns the lifecycle state of the TimerTask associated with this handle.
+	State() TimeoutState
+
+	// ID is Returns the identifier of this handle, which is unique among the timeouts of its Timer.
+	ID() uint64
+
+	// Key is Returns the key of the TimerTask associated with this handle, set by NewKeyedTimeout or WithKey,
+	// or the empty string.
+	Key() string
+
+	// Attempt is Returns the number of runs of the one-shot TimerTask associated with this handle which have
+	// started, that is 1 during the first run and one more on every retry. It is 0 for a periodic task.
+	Attempt() int
+
 	// Cancel is Attempts to cancel the TimerTask associated with this handle.
 	// If the task has been executed or cancelled already, it will return with no side effect.
 	Cancel() bool
+
+	// Done is Returns a channel which is closed when the TimerTask associated with this handle has completed,
+	// or has been cancelled. For a periodic task, it is closed once the task is cancelled or will not be run again.
+	Done() <-chan struct{}
+
+	// Err is Returns nil while Done is not closed. Afterwards it returns ErrCancelled if the timeout has been
+	// cancelled, a *PanicError if the task panicked, or else the error returned by the task.
+	Err() error
+
+	// Reset is Moves the deadline of the TimerTask associated with this handle to delay from now, or the next run
+	// of a periodic task. It returns false with no side effect if the task has been executed or cancelled already.
+	// The move is carried out by the worker, if the timeout expires before that the reset has no effect.
+	Reset(delay time.Duration) bool
 }
 
 // TimerTaskFunc is a function type that implements TimerTask.
@@ -41,6 +81,17 @@ func (f TimerTaskFunc) Run(timeout Timeout) error {
 	return f(timeout)
 }
 
+// ContextTimerTaskFunc is a function type that implements ContextTimerTask.
+type ContextTimerTaskFunc func(context.Context, Timeout) error
+
+func (f ContextTimerTaskFunc) Run(timeout Timeout) error {
+	return f(context.Background(), timeout)
+}
+
+func (f ContextTimerTaskFunc) RunContext(ctx context.Context, timeout Timeout) error {
+	return f(ctx, timeout)
+}
+
 type DataTimerTask[T any] struct {
 	data T
 	f    func(Timeout, T) error
@@ -0,0 +1,112 @@
+package wheeltimer
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Overrun describes a run of a TimerTask which has taken longer than its time limit.
+type Overrun struct {
+	// Timeout is the handle of the task.
+	Timeout Timeout
+	// Limit is the time limit of the task.
+	Limit time.Duration
+	// Elapsed is the time the task has been running for.
+	Elapsed time.Duration
+	// Returned is false when the task is reported at its limit, while it is still running, and true when it is
+	// reported again once it has returned.
+	Returned bool
+}
+
+// OverrunHandler is told about the runs of tasks which take longer than their time limit, see WithOverrunHandler.
+// It is called once when the limit is reached, and once more when the task returns. It must not block.
+type OverrunHandler func(overrun Overrun)
+
+// limitContext is a context which is cancelled with context.DeadlineExceeded at the time limit of a task, on the
+// Clock of the timer, or with the error of its parent if the parent is done first.
+type limitContext struct {
+	context.Context
+	deadline time.Time
+	done     chan struct{}
+	lock     sync.Mutex
+	err      error
+}
+
+func (c *limitContext) Deadline() (time.Time, bool) {
+	if deadline, ok := c.Context.Deadline(); ok && deadline.Before(c.deadline) {
+		return deadline, true
+	}
+	return c.deadline, true
+}
+
+func (c *limitContext) Done() <-chan struct{} {
+	return c.done
+}
+
+func (c *limitContext) Err() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.err
+}
+
+// cancel cancels the context with err, it reports whether the context has been cancelled by this call.
+func (c *limitContext) cancel(err error) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.err != nil {
+		return false
+	}
+	c.err = err
+	close(c.done)
+	return true
+}
+
+// limitTime derives a context from ctx which is cancelled at the time limit of the timeout, and reports the run to
+// the OverrunHandler if it takes longer. The time is measured on the Clock of the timer. The returned function must
+// be called when the task has returned.
+func (timeout *WheelTimeout) limitTime(ctx context.Context) (context.Context, func()) {
+	clock := timeout.timer.clock
+	limit := timeout.timeLimit
+	start := clock.Now()
+	limitCtx := &limitContext{
+		Context:  ctx,
+		deadline: start.Add(limit),
+		done:     make(chan struct{}),
+	}
+	stopParent := context.AfterFunc(ctx, func() {
+		limitCtx.cancel(ctx.Err())
+	})
+
+	overran := false // set before reported is closed
+	reported := make(chan struct{})
+	stopLimit := clock.AfterFunc(limit, func() {
+		defer close(reported)
+		if limitCtx.cancel(context.DeadlineExceeded) {
+			overran = true
+			timeout.overrun(Overrun{Timeout: timeout, Limit: limit, Elapsed: clock.Since(start)})
+		}
+	})
+
+	return limitCtx, func() {
+		stopParent()
+		if !stopLimit() {
+			// the report at the limit comes first.
+			<-reported
+		}
+		if overran {
+			timeout.overrun(Overrun{Timeout: timeout, Limit: limit, Elapsed: clock.Since(start), Returned: true})
+		}
+		limitCtx.cancel(context.Canceled)
+	}
+}
+
+func (timeout *WheelTimeout) overrun(overrun Overrun) {
+	tw := timeout.timer
+	if tw.overrunHandler != nil {
+		tw.overrunHandler(overrun)
+		return
+	}
+	tw.logger.Warn("[wheeltimer] task exceeded its time limit", "task", timeout.Task(), "limit", overrun.Limit,
+		"elapsed", overrun.Elapsed, "returned", overrun.Returned)
+}
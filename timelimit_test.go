@@ -0,0 +1,107 @@
+package wheeltimer
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimeLimit(t *testing.T) {
+	var lock sync.Mutex
+	var overruns []Overrun
+	reported := func() []Overrun {
+		lock.Lock()
+		defer lock.Unlock()
+		return append([]Overrun(nil), overruns...)
+	}
+	// the tasks run on their own goroutines, so that the clock can be advanced while they are running.
+	wheel, advance := newFakeClockWheel(t, time.Millisecond*10,
+		WithExecutor(&defaultExecutor{}),
+		WithTimeLimit(time.Millisecond*20),
+		WithOverrunHandler(func(overrun Overrun) {
+			lock.Lock()
+			defer lock.Unlock()
+			overruns = append(overruns, overrun)
+		}))
+	defer wheel.Stop()
+
+	t.Run("context task", func(t *testing.T) {
+		overruns = nil
+		started := make(chan struct{})
+		timeout, err := wheel.NewTimeout(ContextTimerTaskFunc(func(ctx context.Context, timeout Timeout) error {
+			close(started)
+			<-ctx.Done()
+			return ctx.Err()
+		}), time.Millisecond*10)
+		assert.NoError(t, err)
+
+		advance(time.Millisecond * 20)
+		<-started
+		advance(time.Millisecond * 10)
+		assert.Empty(t, reported())
+		advance(time.Millisecond * 10)
+		<-timeout.Done()
+		assert.ErrorIs(t, timeout.Err(), context.DeadlineExceeded)
+		assert.Equal(t, TimeoutStateFailed, timeout.State())
+
+		overruns := reported()
+		assert.Len(t, overruns, 2)
+		assert.Same(t, timeout, overruns[0].Timeout)
+		assert.False(t, overruns[0].Returned)
+		assert.Equal(t, time.Millisecond*20, overruns[0].Elapsed)
+		assert.True(t, overruns[1].Returned)
+		assert.Equal(t, time.Millisecond*20, overruns[1].Limit)
+		assert.Equal(t, time.Millisecond*20, overruns[1].Elapsed)
+	})
+
+	t.Run("task", func(t *testing.T) {
+		overruns = nil
+		started := make(chan struct{})
+		release := make(chan struct{})
+		timeout, err := wheel.NewTimeout(TimerTaskFunc(func(timeout Timeout) error {
+			close(started)
+			<-release
+			return nil
+		}), time.Millisecond*10)
+		assert.NoError(t, err)
+
+		advance(time.Millisecond * 20)
+		<-started
+		advance(time.Millisecond * 20)
+		assert.Eventually(t, func() bool { return len(reported()) == 1 }, time.Second, time.Millisecond)
+		assert.Equal(t, time.Millisecond*20, reported()[0].Elapsed)
+
+		advance(time.Millisecond * 20)
+		close(release)
+		<-timeout.Done()
+		assert.NoError(t, timeout.Err())
+		overruns := reported()
+		assert.Len(t, overruns, 2)
+		assert.True(t, overruns[1].Returned)
+		assert.Equal(t, time.Millisecond*40, overruns[1].Elapsed)
+	})
+
+	t.Run("within limit", func(t *testing.T) {
+		overruns = nil
+		fast, err := wheel.NewTimeout(TimerTaskFunc(func(timeout Timeout) error {
+			return nil
+		}), time.Millisecond*10)
+		assert.NoError(t, err)
+		release := make(chan struct{})
+		unlimited, err := wheel.NewTimeoutWithOptions(TimerTaskFunc(func(timeout Timeout) error {
+			<-release
+			return nil
+		}), time.Millisecond*10, WithTimeoutTimeLimit(0))
+		assert.NoError(t, err)
+
+		advance(time.Millisecond * 20)
+		<-fast.Done()
+		advance(time.Millisecond * 40)
+		close(release)
+		<-unlimited.Done()
+		assert.Empty(t, reported())
+	})
+}
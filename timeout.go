@@ -1,7 +1,11 @@
 package wheeltimer
 
 import (
+	"errors"
 	"fmt"
+	"log/slog"
+	"math"
+	"runtime/debug"
 	"strings"
 	"sync/atomic"
 	"time"
@@ -9,10 +13,31 @@ import (
 
 type WheelTimeout struct {
 	timer           *WheelTimer
+	id              uint64
 	task            TimerTask
 	state           atomic.Int32
-	deadline        time.Duration
+	deadline        atomic.Int64  // the deadline on the clock of the timer, it is read off the worker by String
+	firstDeadline   time.Duration // the deadline the timeout has been scheduled for, before any reset or retry
+	periodic        *periodic
+	staged          *staged
+	sliding         *sliding
+	ctx             *timeoutContext
+	wallDeadline    int64 // the wall clock deadline in unix nanoseconds, or 0 if the timeout has been scheduled by a delay
 	remainingRounds int
+	retryPolicy     *RetryPolicy
+	timeLimit       time.Duration
+	key             string
+	keyPolicy       KeyPolicy
+	group           *TimeoutGroup
+	parent          *WheelTimeout
+	children        children
+	attempt         atomic.Int32
+
+	resetDeadline atomic.Int64
+	resetting     atomic.Bool // whether the timeout is queued in resetTimeouts
+	resetPending  bool        // whether a reset is applied when the timeout is placed into a bucket, worker only
+
+	completion
 
 	next *WheelTimeout
 	prev *WheelTimeout
@@ -21,10 +46,29 @@ type WheelTimeout struct {
 }
 
 func newWheelTimeout(timer *WheelTimer, task TimerTask, deadline time.Duration) *WheelTimeout {
-	return &WheelTimeout{
-		timer:    timer,
-		task:     task,
-		deadline: deadline,
+	timeout := &WheelTimeout{
+		timer:       timer,
+		id:          timer.lastTimeoutID.Add(1),
+		task:        task,
+		retryPolicy: timer.retryPolicy,
+		timeLimit:   timer.timeLimit,
+	}
+	timeout.setDeadline(deadline)
+	return timeout
+}
+
+func (timeout *WheelTimeout) getDeadline() time.Duration {
+	return time.Duration(timeout.deadline.Load())
+}
+
+func (timeout *WheelTimeout) setDeadline(deadline time.Duration) {
+	timeout.deadline.Store(int64(deadline))
+}
+
+// apply sets the per-timeout options, it must be called before the timeout is scheduled.
+func (timeout *WheelTimeout) apply(opts []TimeoutOption) {
+	for _, opt := range opts {
+		opt(timeout)
 	}
 }
 
@@ -33,25 +77,43 @@ func (timeout *WheelTimeout) Timer() Timer {
 }
 
 func (timeout *WheelTimeout) Task() TimerTask {
+	if s := timeout.staged; s != nil {
+		return s.last.Load().Task
+	}
 	return timeout.task
 }
 
 func (timeout *WheelTimeout) IsExpired() bool {
-	return timeout.State() == timeoutStateExpired
+	return timeout.State() >= TimeoutStateQueued
 }
 
 func (timeout *WheelTimeout) IsCancelled() bool {
-	return timeout.State() == timeoutStateCancelled
+	return timeout.State() == TimeoutStateCancelled
+}
+
+func (timeout *WheelTimeout) State() TimeoutState {
+	return TimeoutState(timeout.state.Load())
+}
+
+func (timeout *WheelTimeout) ID() uint64 {
+	return timeout.id
 }
 
-func (timeout *WheelTimeout) State() timeoutState {
-	return timeoutState(timeout.state.Load())
+func (timeout *WheelTimeout) Key() string {
+	return timeout.key
+}
+
+func (timeout *WheelTimeout) Attempt() int {
+	return int(timeout.attempt.Load())
 }
 
 func (timeout *WheelTimeout) Cancel() bool {
-	if !timeout.state.CompareAndSwap(int32(timeoutStateInit), int32(timeoutStateCancelled)) {
+	if !timeout.state.CompareAndSwap(int32(TimeoutStateScheduled), int32(TimeoutStateCancelled)) {
 		return false
 	}
+	timeout.releaseContext()
+	timeout.complete(ErrCancelled)
+	timeout.cancelChildren(false)
 	// this error does not need to be handled, because if the write fails, it means that the wheeltimer has stopped,
 	// and no one is consuming cancelledTimeouts at this time, so it needs to return true to let the goroutine that calls stop handle it.
 	// else if the wheeltimer has not stopped, always write success.
@@ -59,6 +121,59 @@ func (timeout *WheelTimeout) Cancel() bool {
 	return true
 }
 
+func (timeout *WheelTimeout) Reset(delay time.Duration) bool {
+	if timeout.State() != TimeoutStateScheduled {
+		return false
+	}
+
+	tw := timeout.timer
+	deadline := tw.elapsed() + delay
+	if delay > 0 && deadline < 0 {
+		deadline = math.MaxInt64
+	}
+	if timeout.staged != nil {
+		timeout.staged.resetDelay.Store(int64(delay))
+	}
+	timeout.resetDeadline.Store(int64(deadline))
+	if timeout.resetting.CompareAndSwap(false, true) {
+		if err := tw.resetTimeouts.Put(timeout); err != nil {
+			// the timer has been stopped.
+			timeout.resetting.Store(false)
+			return false
+		}
+	}
+	return true
+}
+
+// reset moves the timeout to the deadline requested by the last call to Reset. It must only be called from the
+// worker goroutine.
+func (timeout *WheelTimeout) reset() {
+	timeout.resetting.Store(false)
+	if timeout.State() != TimeoutStateScheduled {
+		return
+	}
+
+	timeout.wallDeadline = 0
+	if timeout.bucket == nil {
+		// the timeout has not been transferred from the timeouts ring buffer yet, is being rescheduled, or a
+		// fixed-delay run is in flight, which owns the deadline until it has put the timeout back. The deadline
+		// is only changed once the timeout is placed into a bucket.
+		timeout.resetPending = true
+		return
+	}
+	timeout.bucket.unlink(timeout)
+	timeout.setResetDeadline(time.Duration(timeout.resetDeadline.Load()))
+	timeout.timer.addToBucket(timeout)
+}
+
+func (timeout *WheelTimeout) setResetDeadline(deadline time.Duration) {
+	if timeout.staged != nil {
+		timeout.resetStages(deadline)
+		return
+	}
+	timeout.setDeadline(deadline)
+}
+
 func (timeout *WheelTimeout) remove() {
 	if timeout.bucket != nil {
 		timeout.bucket.remove(timeout)
@@ -68,23 +183,151 @@ func (timeout *WheelTimeout) remove() {
 }
 
 func (timeout *WheelTimeout) Expired() {
-	if !timeout.state.CompareAndSwap(int32(timeoutStateInit), int32(timeoutStateExpired)) {
+	if timeout.periodic != nil {
+		timeout.expirePeriodic()
+		return
+	}
+	if timeout.staged != nil && timeout.expireStage() {
+		return
+	}
+	if timeout.sliding != nil && timeout.State() == TimeoutStateScheduled && timeout.slide() {
+		return
+	}
+
+	if !timeout.state.CompareAndSwap(int32(TimeoutStateScheduled), int32(TimeoutStateQueued)) {
 		return
 	}
+	timeout.releaseContext()
+	timeout.cancelChildren(true)
 
-	timeout.timer.executor.Execute(timeout.run)
+	timeout.execute(timeout.run)
+}
+
+// dispatch describes a run of a task which has been handed to the executor.
+type dispatch struct {
+	deadline time.Duration // the deadline the run has been fired for
+	rejected error         // the error of the executor if it has rejected the run
+}
+
+// execute hands f over to the executor of the timer, keeping track of it until it returns. A TimeoutExecutor is
+// told the timeout f belongs to. If a RejectingExecutor or TimeoutRejectingExecutor rejects f, f is called on the
+// rejecting goroutine with the error, and must complete the run without the task.
+func (timeout *WheelTimeout) execute(f func(d dispatch)) {
+	tw := timeout.timer
+	tw.executions.add(timeout)
+	d := dispatch{deadline: timeout.getDeadline()}
+	run := func() {
+		defer tw.executions.done(timeout)
+		f(d)
+	}
+	executeOn(tw.executor, timeout, run, func(err error) {
+		defer tw.executions.done(timeout)
+		f(dispatch{deadline: d.deadline, rejected: err})
+	})
+}
+
+// executeOn hands task, which runs the TimerTask of timeout, over to executor through the most specific interface
+// it implements. If reject is nil, the task is handed over as if executor could not reject it.
+func executeOn(executor Executor, timeout Timeout, task func(), reject func(err error)) {
+	switch executor := executor.(type) {
+	case TimeoutRejectingExecutor:
+		if reject == nil {
+			executor.ExecuteTimeout(timeout, task)
+			return
+		}
+		executor.ExecuteTimeoutOrReject(timeout, task, reject)
+	case TimeoutExecutor:
+		executor.ExecuteTimeout(timeout, task)
+	case RejectingExecutor:
+		if reject == nil {
+			executor.Execute(task)
+			return
+		}
+		executor.ExecuteOrReject(task, reject)
+	default:
+		executor.Execute(task)
+	}
 }
 
-func (timeout *WheelTimeout) run() {
+// run runs the task and completes the timeout with its outcome, or with the error of the executor if it has
+// rejected the run.
+func (timeout *WheelTimeout) run(d dispatch) {
+	var err error
 	defer func() {
 		if r := recover(); r != nil {
 			timeout.timer.panicHandler(r)
+			err = &PanicError{Value: r, Stack: debug.Stack()}
+		}
+		var panicErr *PanicError
+		panicked := errors.As(err, &panicErr)
+		if p := timeout.periodic; p != nil {
+			if panicked {
+				timeout.deadLetter(err)
+			}
+			p.completed(err, timeout.timer.clock.Now())
+			// the last run of a periodic timeout which has been finished completes it.
+			if p.running.Add(-1) == 0 && timeout.State() != TimeoutStateScheduled {
+				timeout.complete(nil)
+			}
+			return
 		}
+		if timeout.retry(err) {
+			return
+		}
+
+		switch {
+		case err == nil:
+			timeout.state.Store(int32(TimeoutStateSucceeded))
+		case panicked:
+			timeout.deadLetter(err)
+			timeout.state.Store(int32(TimeoutStatePanicked))
+		default:
+			timeout.deadLetter(err)
+			timeout.state.Store(int32(TimeoutStateFailed))
+		}
+		timeout.complete(err)
 	}()
-	err := timeout.task.Run(timeout)
+
+	if timeout.periodic == nil {
+		// a rejected attempt counts as well, so that the retry policy gives up on an executor which keeps rejecting.
+		timeout.attempt.Add(1)
+	}
+	runAt := timeout.timer.clock.Now()
+	if d.rejected != nil {
+		err = d.rejected
+		timeout.handleError(err, d, runAt)
+		return
+	}
+	if timeout.periodic == nil {
+		timeout.state.Store(int32(TimeoutStateRunning))
+	}
+	task := timeout.task
+	if s := timeout.staged; s != nil {
+		task = s.final
+	}
+	err = timeout.runTask(task)
 	if err != nil {
-		timeout.timer.logger.Warn("[wheeltimer] task run error", "error", err)
+		timeout.handleError(err, d, runAt)
+	}
+}
+
+// handleError passes the error of a run which has been fired for the deadline of d and started at runAt to the
+// ErrorHandler of the timer, or logs it.
+func (timeout *WheelTimeout) handleError(err error, d dispatch, runAt time.Time) {
+	tw := timeout.timer
+	if tw.errorHandler != nil {
+		tw.errorHandler(timeout, err)
+		return
 	}
+
+	deadline := tw.startTime.Load().(time.Time).Add(d.deadline)
+	tw.logger.Warn("[wheeltimer] task run error",
+		slog.Any("error", err),
+		slog.String("task", fmt.Sprintf("%T", timeout.Task())),
+		slog.Uint64("timeout_id", timeout.id),
+		slog.Time("deadline", deadline),
+		slog.Time("run_at", runAt),
+		slog.Duration("lateness", runAt.Sub(deadline)))
 }
 
 func (timeout *WheelTimeout) String() string {
@@ -92,10 +335,10 @@ func (timeout *WheelTimeout) String() string {
 	if !ok {
 		startTime = time.Time{}
 	}
-	remaining := timeout.deadline - time.Since(startTime)
+	remaining := timeout.getDeadline() - timeout.timer.clock.Since(startTime)
 	var buf strings.Builder
 
-	buf.WriteString(fmt.Sprintf("(deadline: %d", remaining))
+	buf.WriteString("(deadline: ")
 	if remaining > 0 {
 		buf.WriteString(fmt.Sprintf("%d ns later", remaining))
 	} else if remaining < 0 {
@@ -108,6 +351,6 @@ func (timeout *WheelTimeout) String() string {
 		buf.WriteString(", cancelled")
 	}
 
-	buf.WriteString(fmt.Sprintf(", task: %v)", timeout.task))
+	buf.WriteString(fmt.Sprintf(", task: %v)", timeout.Task()))
 	return buf.String()
 }
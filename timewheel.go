@@ -1,6 +1,7 @@
 package wheeltimer
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"math"
@@ -16,17 +17,58 @@ type workerState int32
 const (
 	workerStateInit workerState = iota
 	workerStateStarted
+	workerStateDraining
 	workerStateShutdown
 )
 
-type timeoutState int32
+// TimeoutState is the lifecycle state of a Timeout.
+type TimeoutState int32
 
 const (
-	timeoutStateInit timeoutState = iota
-	timeoutStateCancelled
-	timeoutStateExpired
+	// TimeoutStateScheduled is the state of a timeout waiting for its deadline. A periodic timeout stays
+	// in this state until it is cancelled or will not be run again.
+	TimeoutStateScheduled TimeoutState = iota
+	// TimeoutStateCancelled is the state of a timeout which has been cancelled before its deadline.
+	TimeoutStateCancelled
+	// TimeoutStateQueued is the state of a timeout which has fired and whose task has been handed to the executor.
+	TimeoutStateQueued
+	// TimeoutStateRunning is the state of a timeout whose task is running.
+	TimeoutStateRunning
+	// TimeoutStateSucceeded is the state of a timeout whose task has returned nil, or of a periodic timeout
+	// which has been run for the last time.
+	TimeoutStateSucceeded
+	// TimeoutStateFailed is the state of a timeout whose task has returned an error.
+	TimeoutStateFailed
+	// TimeoutStatePanicked is the state of a timeout whose task has panicked.
+	TimeoutStatePanicked
 )
 
+func (s TimeoutState) String() string {
+	switch s {
+	case TimeoutStateScheduled:
+		return "scheduled"
+	case TimeoutStateCancelled:
+		return "cancelled"
+	case TimeoutStateQueued:
+		return "queued"
+	case TimeoutStateRunning:
+		return "running"
+	case TimeoutStateSucceeded:
+		return "succeeded"
+	case TimeoutStateFailed:
+		return "failed"
+	case TimeoutStatePanicked:
+		return "panicked"
+	default:
+		return fmt.Sprintf("TimeoutState(%d)", int32(s))
+	}
+}
+
+// IsDone reports whether s is a final state.
+func (s TimeoutState) IsDone() bool {
+	return s == TimeoutStateCancelled || s >= TimeoutStateSucceeded
+}
+
 type WheelTimer struct {
 	*option
 
@@ -35,16 +77,36 @@ type WheelTimer struct {
 	mask         int
 	tick         int
 
+	// overflowWheels holds the coarser wheels of the hierarchical mode, they are created on demand.
+	overflowWheels [][]*WheelBucket
+
+	// skew is the offset of the wall clock to the monotonic clock measured by the last wall clock check.
+	skew time.Duration
+
 	workerState          atomic.Int32
 	startTime            atomic.Value
 	startTimeInitializer sync.WaitGroup
 
 	timeouts          *RingBuffer
 	cancelledTimeouts *RingBuffer
+	resetTimeouts     *RingBuffer
 
 	unprocessedTimeouts []*WheelTimeout
+	rescheduledTimeouts []*WheelTimeout
+	cancelledOnWorker   []*WheelTimeout // the timeouts cancelled by the worker, see cancelOnWorker
+	requeueLock         sync.Mutex
+	requeuedTimeouts    []*WheelTimeout // the timeouts put back while the timeouts ring buffer was full, see requeue
+	requeueClosed       bool            // set once the worker has collected the requeued timeouts for the last time
 	pendingTimeouts     atomic.Int64
+	lastTimeoutID       atomic.Uint64
+	executions          executions
+	keys                keyedTimeouts
+
+	// ctx is the parent of the contexts passed to ContextTimerTask, it is cancelled when the timer is stopped.
+	ctx       context.Context
+	cancelCtx context.CancelFunc
 
+	stopCh   chan struct{} // closed to wake up the worker when the timer is stopped
 	closedCh chan struct{}
 }
 
@@ -68,13 +130,18 @@ func NewWheelTimer(tickDuration time.Duration, ticksPerWheel uint32, opts ...Whe
 		tickDuration = time.Millisecond
 	}
 
+	ctx, cancel := context.WithCancel(context.Background())
 	wt := &WheelTimer{
 		tickDuration:      tickDuration,
 		wheel:             wheel,
 		mask:              mask,
 		timeouts:          NewRingBuffer(o.ringBufferSize, o.ringBufferOptions...),
 		cancelledTimeouts: NewRingBuffer(o.ringBufferSize, o.ringBufferOptions...),
+		resetTimeouts:     NewRingBuffer(o.ringBufferSize, o.ringBufferOptions...),
 		option:            o,
+		ctx:               ctx,
+		cancelCtx:         cancel,
+		stopCh:            make(chan struct{}),
 		closedCh:          make(chan struct{}),
 	}
 	wt.startTimeInitializer.Add(1)
@@ -90,6 +157,8 @@ func (tw *WheelTimer) Start() error {
 		}
 	case workerStateStarted:
 		break
+	case workerStateDraining:
+		return ErrShuttingDown
 	case workerStateShutdown:
 		return fmt.Errorf("cannot be started once stopped")
 	default:
@@ -103,7 +172,8 @@ func (tw *WheelTimer) Start() error {
 }
 
 func (tw *WheelTimer) Stop() []Timeout {
-	if !tw.workerState.CompareAndSwap(int32(workerStateStarted), int32(workerStateShutdown)) {
+	if !tw.workerState.CompareAndSwap(int32(workerStateStarted), int32(workerStateShutdown)) &&
+		!tw.workerState.CompareAndSwap(int32(workerStateDraining), int32(workerStateShutdown)) {
 		// nolint: staticcheck // SA9003 not implemented yet
 		if tw.workerState.Swap(int32(workerStateShutdown)) != int32(workerStateShutdown) {
 			//TODO
@@ -111,9 +181,62 @@ func (tw *WheelTimer) Stop() []Timeout {
 		return nil
 	}
 
-	// wait for the worker to be stopped
+	return tw.stop()
+}
+
+// StopAndWait stops the timer like Stop, and then waits for the tasks which are still running on the executor.
+// If ctx is done before they have returned, the returned error is a RunningTasksError listing them.
+func (tw *WheelTimer) StopAndWait(ctx context.Context) ([]Timeout, error) {
+	cancelled := tw.Stop()
+	return cancelled, tw.executions.wait(ctx)
+}
+
+// Shutdown stops the timer gracefully. New timeouts are rejected with ErrShuttingDown, while the pending ones are
+// left to expire normally; periodic timeouts run once more at their next deadline and are not rescheduled. Once
+// the wheel is empty, Shutdown waits for the tasks still running on the executor.
+//
+// If ctx is done before that, Shutdown falls back to Stop and returns the timeouts which have not been processed,
+// together with the error of ctx. If only the running tasks did not return in time, the error is a
+// RunningTasksError listing them.
+func (tw *WheelTimer) Shutdown(ctx context.Context) ([]Timeout, error) {
+	if !tw.workerState.CompareAndSwap(int32(workerStateStarted), int32(workerStateDraining)) {
+		if tw.workerState.CompareAndSwap(int32(workerStateInit), int32(workerStateShutdown)) {
+			return nil, nil
+		}
+		if tw.State() == workerStateShutdown {
+			return nil, nil
+		}
+	}
+
+	select {
+	case <-tw.closedCh:
+	case <-ctx.Done():
+		if tw.workerState.CompareAndSwap(int32(workerStateDraining), int32(workerStateShutdown)) {
+			return tw.stop(), ctx.Err()
+		}
+		return nil, ctx.Err()
+	}
+
+	if !tw.workerState.CompareAndSwap(int32(workerStateDraining), int32(workerStateShutdown)) {
+		// stopped by a concurrent Stop, which has already dealt with the unprocessed timeouts.
+		return nil, tw.executions.wait(ctx)
+	}
+	cancelled := tw.cancelUnprocessed()
+	err := tw.executions.wait(ctx)
+	tw.cancelCtx()
+	return cancelled, err
+}
+
+func (tw *WheelTimer) stop() []Timeout {
+	// cancel the context of the running tasks, and wait for the worker to be stopped
+	tw.cancelCtx()
+	close(tw.stopCh)
 	<-tw.closedCh
 
+	return tw.cancelUnprocessed()
+}
+
+func (tw *WheelTimer) cancelUnprocessed() []Timeout {
 	unprocessed := tw.unprocessedTimeouts
 	cancelled := make([]Timeout, 0, len(unprocessed))
 	for _, timeout := range unprocessed {
@@ -126,59 +249,112 @@ func (tw *WheelTimer) Stop() []Timeout {
 }
 
 func (tw *WheelTimer) NewTimeout(task TimerTask, delay time.Duration) (Timeout, error) {
+	return tw.NewTimeoutWithOptions(task, delay)
+}
+
+// NewTimeoutWithOptions schedules the specified TimerTask for one-time execution after the specified delay, like
+// NewTimeout, with the given per-timeout options.
+func (tw *WheelTimer) NewTimeoutWithOptions(task TimerTask, delay time.Duration, opts ...TimeoutOption) (Timeout, error) {
+	timeout := newWheelTimeout(tw, task, 0)
+	timeout.apply(opts)
+	if err := tw.schedule(timeout, delay); err != nil {
+		return nil, err
+	}
+	return timeout, nil
+}
+
+// schedule sets the deadline of timeout to delay from now and hands it over to the worker.
+func (tw *WheelTimer) schedule(timeout *WheelTimeout, delay time.Duration) error {
 	pendingTimeoutsCount := tw.pendingTimeouts.Add(1)
 
 	if tw.maxPendingTimeouts > 0 && pendingTimeoutsCount > tw.maxPendingTimeouts {
 		tw.pendingTimeouts.Add(-1)
-		return nil, fmt.Errorf("pending timeouts (%d) is greater than maxPendingTimeouts (%d)", pendingTimeoutsCount, tw.maxPendingTimeouts)
+		return fmt.Errorf("pending timeouts (%d) is greater than maxPendingTimeouts (%d)", pendingTimeoutsCount, tw.maxPendingTimeouts)
 	}
 
 	err := tw.Start()
 	if err != nil {
 		tw.pendingTimeouts.Add(-1)
-		return nil, err
+		return err
 	}
 
-	deadline := time.Since(tw.startTime.Load().(time.Time)) + delay
+	deadline := tw.elapsed() + delay
 	if delay > 0 && deadline < 0 {
 		deadline = math.MaxInt64
 	}
 
-	timeout := newWheelTimeout(tw, task, deadline)
+	timeout.setDeadline(deadline)
+	timeout.firstDeadline = deadline
+	if group := timeout.group; group != nil {
+		// the timeout joins the group before it can be cancelled by CancelAll, and leaves it once it has completed.
+		group.add(timeout)
+	}
 	err = tw.timeouts.Put(timeout)
 	if err != nil {
 		tw.pendingTimeouts.Add(-1)
-		return nil, err
+		if timeout.group != nil {
+			timeout.group.remove(timeout)
+		}
+		return err
+	}
+	if group := timeout.group; group != nil {
+		timeout.afterDone(func() {
+			group.remove(timeout)
+		})
+	}
+	if timeout.parent != nil {
+		timeout.attachToParent()
 	}
 
-	return timeout, nil
+	return nil
 }
 
 func (tw *WheelTimer) State() workerState {
 	return workerState(tw.workerState.Load())
 }
 
+// isRunning reports whether the worker should process the next tick. A draining worker stops once every
+// pending timeout has been processed.
+func (tw *WheelTimer) isRunning() bool {
+	switch tw.State() {
+	case workerStateStarted:
+		return true
+	case workerStateDraining:
+		return tw.pendingTimeouts.Load() > 0
+	default:
+		return false
+	}
+}
+
 func (tw *WheelTimer) PendingTimeouts() int64 {
 	return tw.pendingTimeouts.Load()
 }
 
 func (tw *WheelTimer) run() {
-	tw.startTime.Store(time.Now())
+	tw.startTime.Store(tw.clock.Now())
 	tw.startTimeInitializer.Done()
 
 	defer func() {
 		// release the remaining timeouts
 		tw.timeouts.Dispose()
 		tw.cancelledTimeouts.Dispose()
+		tw.resetTimeouts.Dispose()
 		close(tw.closedCh)
 	}()
 
-	for tw.State() == workerStateStarted {
+	for tw.isRunning() {
 		deadline := tw.waitForNextTick()
 		if deadline > 0 {
 			idx := tw.tick & tw.mask
 			tw.processCancelledTasks()
+			if tw.wallClockThreshold > 0 {
+				tw.checkWallClock()
+			}
+			tw.processResetTasks()
 			bucket := tw.wheel[idx]
+			if tw.tick&tw.mask == 0 && len(tw.overflowWheels) > 0 {
+				tw.cascade()
+			}
 			tw.transferTimeoutsToBuckets()
 			bucket.expireTimeouts(deadline)
 			tw.tick++
@@ -188,6 +364,17 @@ func (tw *WheelTimer) run() {
 	for _, bucket := range tw.wheel {
 		tw.unprocessedTimeouts = bucket.clearTimeouts(tw.unprocessedTimeouts)
 	}
+	for _, wheel := range tw.overflowWheels {
+		for _, bucket := range wheel {
+			tw.unprocessedTimeouts = bucket.clearTimeouts(tw.unprocessedTimeouts)
+		}
+	}
+	for _, timeout := range tw.rescheduledTimeouts {
+		if !timeout.IsCancelled() {
+			tw.unprocessedTimeouts = append(tw.unprocessedTimeouts, timeout)
+		}
+	}
+	tw.rescheduledTimeouts = nil
 
 	for {
 		data, err := tw.timeouts.PollNonBlocking(0)
@@ -199,6 +386,16 @@ func (tw *WheelTimer) run() {
 			tw.unprocessedTimeouts = append(tw.unprocessedTimeouts, timeout)
 		}
 	}
+	tw.requeueLock.Lock()
+	requeued := tw.requeuedTimeouts
+	tw.requeuedTimeouts = nil
+	tw.requeueClosed = true
+	tw.requeueLock.Unlock()
+	for _, timeout := range requeued {
+		if !timeout.IsCancelled() {
+			tw.unprocessedTimeouts = append(tw.unprocessedTimeouts, timeout)
+		}
+	}
 	tw.processCancelledTasks()
 }
 
@@ -207,7 +404,7 @@ func (tw *WheelTimer) waitForNextTick() time.Duration {
 	startTime := tw.startTime.Load().(time.Time)
 
 	for {
-		currentTime := time.Since(startTime)
+		currentTime := tw.clock.Since(startTime)
 		sleepTimeMs := (deadline - currentTime + time.Duration(999999)).Milliseconds()
 
 		if sleepTimeMs <= 0 {
@@ -219,11 +416,26 @@ func (tw *WheelTimer) waitForNextTick() time.Duration {
 		}
 
 		// microsecond sleep has different precision on different systems, but millisecond sleep is more stable
-		time.Sleep(time.Duration(sleepTimeMs))
+		select {
+		case <-tw.clock.After(time.Duration(sleepTimeMs) * time.Millisecond):
+		case <-tw.stopCh:
+			return 0
+		}
 	}
 }
 
+// elapsed returns the time passed since the worker has been started.
+func (tw *WheelTimer) elapsed() time.Duration {
+	return tw.clock.Since(tw.startTime.Load().(time.Time))
+}
+
 func (tw *WheelTimer) processCancelledTasks() {
+	for i, timeout := range tw.cancelledOnWorker {
+		tw.cancelledOnWorker[i] = nil
+		timeout.remove()
+	}
+	tw.cancelledOnWorker = tw.cancelledOnWorker[:0]
+
 	for {
 		data, err := tw.cancelledTimeouts.PollNonBlocking(0)
 		if errors.Is(err, ErrEmpty) {
@@ -233,6 +445,16 @@ func (tw *WheelTimer) processCancelledTasks() {
 	}
 }
 
+func (tw *WheelTimer) processResetTasks() {
+	for {
+		data, err := tw.resetTimeouts.PollNonBlocking(0)
+		if errors.Is(err, ErrEmpty) {
+			break
+		}
+		data.(*WheelTimeout).reset()
+	}
+}
+
 func (tw *WheelTimer) transferTimeoutsToBuckets() {
 	// transfer only max. 100000 timeouts per tick to prevent a thread to stale the workerThread when it just
 	// adds new timeouts in a loop.
@@ -244,24 +466,133 @@ func (tw *WheelTimer) transferTimeoutsToBuckets() {
 
 		timeout := data.(*WheelTimeout)
 
-		if timeout.State() == timeoutStateCancelled {
+		if timeout.State() == TimeoutStateCancelled {
 			continue
 		}
 
-		calculated := int(timeout.deadline / tw.tickDuration)
-		timeout.remainingRounds = (calculated - tw.tick) / len(tw.wheel)
+		tw.addToBucket(timeout)
+	}
 
-		var ticks int
-		if calculated < tw.tick {
-			ticks = tw.tick
-		} else {
-			ticks = int(calculated)
+	tw.requeueLock.Lock()
+	requeued := tw.requeuedTimeouts
+	tw.requeuedTimeouts = nil
+	tw.requeueLock.Unlock()
+	for _, timeout := range requeued {
+		if timeout.State() == TimeoutStateCancelled {
+			continue
 		}
-		stopIndex := (int)(ticks & tw.mask)
+		tw.addToBucket(timeout)
+	}
+
+	// periodic timeouts that fired during the last tick are placed back into the wheel here instead of
+	// in expireTimeouts, so that they never land in the bucket which is being expired.
+	rescheduled := tw.rescheduledTimeouts
+	tw.rescheduledTimeouts = tw.rescheduledTimeouts[:0]
+	for i, timeout := range rescheduled {
+		rescheduled[i] = nil
+		if timeout.State() == TimeoutStateCancelled {
+			continue
+		}
+		tw.addToBucket(timeout)
+	}
+}
+
+func (tw *WheelTimer) addToBucket(timeout *WheelTimeout) {
+	if timeout.resetPending {
+		timeout.resetPending = false
+		timeout.wallDeadline = 0
+		timeout.setResetDeadline(time.Duration(timeout.resetDeadline.Load()))
+	}
+	if timeout.wallDeadline != 0 && tw.wallClockThreshold > 0 {
+		// the deadline was calculated before the last wall clock check.
+		timeout.setDeadline(tw.wallClockDeadline(timeout))
+	}
 
-		bucket := tw.wheel[stopIndex]
-		bucket.addTimeout(timeout)
+	calculated := int(timeout.getDeadline() / tw.tickDuration)
+	if tw.hierarchical && calculated-tw.tick >= len(tw.wheel) {
+		tw.addToOverflowBucket(timeout, calculated)
+		return
 	}
+
+	timeout.remainingRounds = (calculated - tw.tick) / len(tw.wheel)
+
+	var ticks int
+	if calculated < tw.tick {
+		ticks = tw.tick
+	} else {
+		ticks = int(calculated)
+	}
+	stopIndex := (int)(ticks & tw.mask)
+
+	bucket := tw.wheel[stopIndex]
+	bucket.addTimeout(timeout)
+}
+
+// addToOverflowBucket places a timeout that does not fit into the wheel into the finest overflow wheel that can
+// hold it. The bucket of the overflow wheel at level n covers len(wheel)^(n+1) ticks.
+func (tw *WheelTimer) addToOverflowBucket(timeout *WheelTimeout, calculated int) {
+	size := len(tw.wheel)
+	slot, current := calculated, tw.tick
+	for level := 0; ; level++ {
+		slot /= size
+		current /= size
+		// the current slot of an overflow wheel has already been cascaded, so it may only
+		// receive the timeouts of the slot one round ahead.
+		if slot-current > size {
+			continue
+		}
+
+		for len(tw.overflowWheels) <= level {
+			tw.overflowWheels = append(tw.overflowWheels, newTimerWheel(uint32(size)))
+		}
+		timeout.remainingRounds = 0
+		tw.overflowWheels[level][slot&tw.mask].addTimeout(timeout)
+		return
+	}
+}
+
+// cascade moves the timeouts of the overflow buckets whose range starts at the current tick down to the finer
+// wheels, starting with the coarsest wheel so that its timeouts can be cascaded further in the same tick.
+func (tw *WheelTimer) cascade() {
+	size := len(tw.wheel)
+	levels := 0
+	for t := tw.tick; levels < len(tw.overflowWheels) && t%size == 0; t /= size {
+		levels++
+	}
+
+	for level := levels - 1; level >= 0; level-- {
+		slot := tw.tick
+		for i := 0; i <= level; i++ {
+			slot /= size
+		}
+
+		tw.overflowWheels[level][slot&tw.mask].drainTimeouts(tw.addToBucket)
+	}
+}
+
+// reschedule puts a periodic timeout back into the wheel at the next tick. It must only be called from the worker
+// goroutine.
+func (tw *WheelTimer) reschedule(timeout *WheelTimeout) {
+	tw.pendingTimeouts.Add(1)
+	tw.rescheduledTimeouts = append(tw.rescheduledTimeouts, timeout)
+}
+
+// requeue puts a timeout whose task has been run back into the wheel without blocking, so that it may be called
+// from a task which runs on the worker goroutine. If the timeouts ring buffer is full, the timeout is held until the
+// worker transfers it at the next tick. It returns ErrDisposed once the timer has been stopped.
+func (tw *WheelTimer) requeue(timeout *WheelTimeout) error {
+	ok, err := tw.timeouts.Offer(timeout)
+	if err != nil || ok {
+		return err
+	}
+
+	tw.requeueLock.Lock()
+	defer tw.requeueLock.Unlock()
+	if tw.requeueClosed {
+		return ErrDisposed
+	}
+	tw.requeuedTimeouts = append(tw.requeuedTimeouts, timeout)
+	return nil
 }
 
 func newTimerWheel(ticksPerWheel uint32) []*WheelBucket {
@@ -1,9 +1,14 @@
 package wheeltimer
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"fmt"
+	"log/slog"
 	"math/rand"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -42,3 +47,552 @@ func TestTimerWheel(t *testing.T) {
 
 	wg.Wait()
 }
+
+func TestPeriodicTimeout(t *testing.T) {
+	wheel, err := NewWheelTimer(time.Millisecond*10, 64)
+	assert.NoError(t, err)
+	defer wheel.Stop()
+
+	var runs atomic.Int32
+	timeout, err := wheel.NewPeriodicTimeout(TimerTaskFunc(func(timeout Timeout) error {
+		runs.Add(1)
+		return nil
+	}), time.Millisecond*20, time.Millisecond*20)
+	assert.NoError(t, err)
+
+	assert.Eventually(t, func() bool { return runs.Load() >= 3 }, time.Second*2, time.Millisecond*5)
+	assert.False(t, timeout.IsExpired())
+
+	assert.True(t, timeout.Cancel())
+	assert.False(t, timeout.Cancel())
+	time.Sleep(time.Millisecond * 50)
+	count := runs.Load()
+	time.Sleep(time.Millisecond * 100)
+	assert.Equal(t, count, runs.Load())
+	assert.Eventually(t, func() bool { return wheel.PendingTimeouts() == 0 }, time.Second, time.Millisecond)
+}
+
+func TestFixedDelayTimeout(t *testing.T) {
+	wheel, err := NewWheelTimer(time.Millisecond*10, 64)
+	assert.NoError(t, err)
+	defer wheel.Stop()
+
+	var running atomic.Int32
+	var overlapped atomic.Bool
+	release := make(chan struct{})
+	errRun := fmt.Errorf("run failed")
+	timeout, err := wheel.NewFixedDelayTimeout(TimerTaskFunc(func(timeout Timeout) error {
+		if running.Add(1) > 1 {
+			overlapped.Store(true)
+		}
+		defer running.Add(-1)
+		if timeout.(PeriodicTimeout).RunCount() == 1 {
+			<-release
+			return errRun
+		}
+		return nil
+	}), time.Millisecond*10, time.Millisecond*10)
+	assert.NoError(t, err)
+
+	assert.Eventually(t, func() bool { return running.Load() == 1 && timeout.RunCount() == 1 }, time.Second, time.Millisecond)
+	// the second run blocks, so no other run may be started in the meantime.
+	time.Sleep(time.Millisecond * 50)
+	assert.Equal(t, int64(1), timeout.RunCount())
+
+	close(release)
+	assert.Eventually(t, func() bool { return timeout.RunCount() == 2 }, time.Second, time.Millisecond)
+	assert.ErrorIs(t, timeout.LastError(), errRun)
+	assert.False(t, timeout.LastCompletion().IsZero())
+
+	assert.Eventually(t, func() bool { return timeout.RunCount() >= 3 && timeout.LastError() == nil }, time.Second, time.Millisecond)
+	assert.True(t, timeout.Cancel())
+	time.Sleep(time.Millisecond * 30)
+	count := timeout.RunCount()
+	time.Sleep(time.Millisecond * 50)
+	assert.Equal(t, count, timeout.RunCount())
+	assert.False(t, overlapped.Load())
+	assert.Equal(t, int64(0), wheel.PendingTimeouts())
+}
+
+func TestFixedDelayTimeout_CancelWhileRunning(t *testing.T) {
+	wheel, err := NewWheelTimer(time.Millisecond*10, 64)
+	assert.NoError(t, err)
+	defer wheel.Stop()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	timeout, err := wheel.NewFixedDelayTimeout(TimerTaskFunc(func(timeout Timeout) error {
+		close(started)
+		<-release
+		return nil
+	}), time.Millisecond*10, time.Millisecond*10)
+	assert.NoError(t, err)
+
+	<-started
+	assert.True(t, timeout.Cancel())
+	close(release)
+
+	assert.Eventually(t, func() bool { return timeout.RunCount() == 1 }, time.Second, time.Millisecond)
+	time.Sleep(time.Millisecond * 50)
+	assert.Equal(t, int64(1), timeout.RunCount())
+	assert.Equal(t, int64(0), wheel.PendingTimeouts())
+}
+
+func TestFixedDelayTimeout_ResetWhileRunning(t *testing.T) {
+	wheel, err := NewWheelTimer(time.Millisecond*10, 64)
+	assert.NoError(t, err)
+	defer wheel.Stop()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	timeout, err := wheel.NewFixedDelayTimeout(TimerTaskFunc(func(timeout Timeout) error {
+		if timeout.(PeriodicTimeout).RunCount() == 0 {
+			close(started)
+			<-release
+		}
+		return nil
+	}), time.Millisecond*10, time.Millisecond*5)
+	assert.NoError(t, err)
+
+	<-started
+	assert.True(t, timeout.Reset(time.Hour))
+	// let the worker process the reset while the run is still in flight.
+	time.Sleep(time.Millisecond * 30)
+	close(release)
+
+	assert.Eventually(t, func() bool { return timeout.RunCount() == 1 }, time.Second, time.Millisecond)
+	time.Sleep(time.Millisecond * 50)
+	assert.Equal(t, int64(1), timeout.RunCount())
+	assert.Equal(t, TimeoutStateScheduled, timeout.State())
+	assert.Equal(t, int64(1), wheel.PendingTimeouts())
+}
+
+type onceSchedule struct {
+	at time.Time
+}
+
+func (s onceSchedule) Next(t time.Time) time.Time {
+	if t.Before(s.at) {
+		return s.at
+	}
+	return time.Time{}
+}
+
+func TestCronTimeout(t *testing.T) {
+	wheel, err := NewWheelTimer(time.Millisecond*10, 64)
+	assert.NoError(t, err)
+	defer wheel.Stop()
+
+	_, err = wheel.NewCronTimeout("* * *", TimerTaskFunc(func(timeout Timeout) error { return nil }))
+	assert.Error(t, err)
+
+	var runs atomic.Int32
+	timeout, err := wheel.NewCronTimeout("@every 20ms", TimerTaskFunc(func(timeout Timeout) error {
+		runs.Add(1)
+		return nil
+	}))
+	assert.NoError(t, err)
+	assert.Eventually(t, func() bool { return timeout.RunCount() >= 3 }, time.Second*2, time.Millisecond*5)
+	assert.True(t, timeout.Cancel())
+
+	once, err := wheel.NewScheduleTimeout(onceSchedule{at: time.Now().Add(time.Millisecond * 30)}, TimerTaskFunc(func(timeout Timeout) error {
+		return nil
+	}))
+	assert.NoError(t, err)
+	assert.Eventually(t, func() bool { return once.RunCount() == 1 && once.IsExpired() }, time.Second, time.Millisecond*5)
+	assert.False(t, once.Cancel())
+	assert.Eventually(t, func() bool { return wheel.PendingTimeouts() == 0 }, time.Second, time.Millisecond*5)
+}
+
+type inlineExecutor struct{}
+
+func (inlineExecutor) Execute(task func()) {
+	task()
+}
+
+func TestHierarchicalWheel(t *testing.T) {
+	wheel, err := NewWheelTimer(time.Millisecond, 8, WithHierarchicalWheel(true), WithExecutor(inlineExecutor{}))
+	assert.NoError(t, err)
+
+	fired := make(map[int]int)
+	deadlines := []int{3, 8, 9, 63, 64, 65, 100, 511, 512, 4000}
+	for _, deadline := range deadlines {
+		deadline := deadline
+		timeout := newWheelTimeout(wheel, TimerTaskFunc(func(timeout Timeout) error {
+			fired[deadline] = wheel.tick
+			return nil
+		}), time.Duration(deadline)*time.Millisecond)
+		wheel.addToBucket(timeout)
+	}
+	assert.Len(t, wheel.overflowWheels, 3)
+
+	// drive the wheel by hand, one tick at a time.
+	for wheel.tick <= 4000 {
+		if wheel.tick&wheel.mask == 0 {
+			wheel.cascade()
+		}
+		wheel.wheel[wheel.tick&wheel.mask].expireTimeouts(time.Duration(wheel.tick+1) * time.Millisecond)
+		wheel.tick++
+	}
+
+	for _, deadline := range deadlines {
+		assert.Equal(t, deadline, fired[deadline], "deadline %d", deadline)
+	}
+}
+
+func TestHierarchicalWheel_Run(t *testing.T) {
+	wheel, err := NewWheelTimer(time.Millisecond, 8, WithHierarchicalWheel(true))
+	assert.NoError(t, err)
+	defer wheel.Stop()
+
+	var wg sync.WaitGroup
+	for _, delay := range []time.Duration{5, 30, 100, 300} {
+		delay := delay * time.Millisecond
+		wg.Add(1)
+		start := time.Now()
+		_, err := wheel.NewTimeout(TimerTaskFunc(func(timeout Timeout) error {
+			defer wg.Done()
+			assert.GreaterOrEqual(t, time.Since(start), delay)
+			return nil
+		}), delay)
+		assert.NoError(t, err)
+	}
+	wg.Wait()
+}
+
+func newFakeClockWheel(t *testing.T, tickDuration time.Duration, opts ...WheelTimerOption) (*WheelTimer, func(time.Duration)) {
+	clock := NewFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	opts = append([]WheelTimerOption{WithClock(clock), WithExecutor(inlineExecutor{})}, opts...)
+	wheel, err := NewWheelTimer(tickDuration, 8, opts...)
+	assert.NoError(t, err)
+	assert.NoError(t, wheel.Start())
+
+	// advance moves the clock tick by tick, and returns once the worker has processed every tick.
+	advance := func(d time.Duration) {
+		for ; d > 0; d -= tickDuration {
+			clock.BlockUntil(1)
+			clock.Advance(tickDuration)
+		}
+		clock.BlockUntil(1)
+	}
+	return wheel, advance
+}
+
+func TestTimerWheel_FakeClock(t *testing.T) {
+	wheel, advance := newFakeClockWheel(t, time.Millisecond*10)
+	defer wheel.Stop()
+
+	var fired atomic.Int32
+	timeout, err := wheel.NewTimeout(TimerTaskFunc(func(timeout Timeout) error {
+		fired.Add(1)
+		return nil
+	}), time.Millisecond*35)
+	assert.NoError(t, err)
+	assert.Contains(t, timeout.(*WheelTimeout).String(), "(deadline: 35000000 ns later, task: ")
+
+	advance(time.Millisecond * 30)
+	assert.Equal(t, int32(0), fired.Load())
+	assert.False(t, timeout.IsExpired())
+
+	advance(time.Millisecond * 10)
+	assert.Equal(t, int32(1), fired.Load())
+	assert.True(t, timeout.IsExpired())
+	assert.Contains(t, timeout.(*WheelTimeout).String(), "(deadline: 5000000 ns ago, task: ")
+}
+
+func TestTimeoutString_Concurrent(t *testing.T) {
+	wheel, err := NewWheelTimer(time.Millisecond, 8)
+	assert.NoError(t, err)
+	defer wheel.Stop()
+
+	// the worker moves the deadline of a periodic timeout while it is formatted by its task.
+	var formatted atomic.Int32
+	timeout, err := wheel.NewPeriodicTimeout(TimerTaskFunc(func(timeout Timeout) error {
+		_ = timeout.(*WheelTimeout).String()
+		formatted.Add(1)
+		return nil
+	}), time.Millisecond, time.Millisecond)
+	assert.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		_ = timeout.(*WheelTimeout).String()
+		return formatted.Load() >= 10
+	}, time.Second, time.Millisecond)
+	assert.True(t, timeout.Cancel())
+}
+
+func TestPeriodicTimeout_FakeClock(t *testing.T) {
+	wheel, advance := newFakeClockWheel(t, time.Millisecond*10)
+	defer wheel.Stop()
+
+	timeout, err := wheel.NewPeriodicTimeout(TimerTaskFunc(func(timeout Timeout) error {
+		return nil
+	}), time.Millisecond*20, time.Millisecond*30)
+	assert.NoError(t, err)
+
+	advance(time.Millisecond * 20)
+	assert.Equal(t, int64(0), timeout.RunCount())
+	advance(time.Millisecond * 10)
+	assert.Equal(t, int64(1), timeout.RunCount())
+	// runs are due at 20ms, 50ms, 80ms, ... and fire at the end of the tick containing them.
+	advance(time.Millisecond * 30)
+	assert.Equal(t, int64(2), timeout.RunCount())
+	advance(time.Millisecond * 90)
+	assert.Equal(t, int64(5), timeout.RunCount())
+
+	// a period shorter than a tick runs once per tick.
+	short, err := wheel.NewPeriodicTimeout(TimerTaskFunc(func(timeout Timeout) error {
+		return nil
+	}), 0, time.Millisecond*3)
+	assert.NoError(t, err)
+	advance(time.Millisecond * 50)
+	assert.Equal(t, int64(5), short.RunCount())
+}
+
+func TestShutdown(t *testing.T) {
+	wheel, err := NewWheelTimer(time.Millisecond*10, 64)
+	assert.NoError(t, err)
+
+	var finished atomic.Int32
+	for i := 1; i <= 3; i++ {
+		_, err := wheel.NewTimeout(TimerTaskFunc(func(timeout Timeout) error {
+			time.Sleep(time.Millisecond * 30)
+			finished.Add(1)
+			return nil
+		}), time.Millisecond*time.Duration(20*i))
+		assert.NoError(t, err)
+	}
+	periodic, err := wheel.NewPeriodicTimeout(TimerTaskFunc(func(timeout Timeout) error {
+		return nil
+	}), time.Millisecond*10, time.Millisecond*10)
+	assert.NoError(t, err)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		unprocessed, err := wheel.Shutdown(context.Background())
+		assert.NoError(t, err)
+		assert.Empty(t, unprocessed)
+	}()
+
+	assert.Eventually(t, func() bool { return wheel.State() == workerStateDraining || wheel.State() == workerStateShutdown }, time.Second, time.Millisecond)
+	_, err = wheel.NewTimeout(TimerTaskFunc(func(timeout Timeout) error { return nil }), 0)
+	assert.ErrorIs(t, err, ErrShuttingDown)
+
+	<-done
+	assert.Equal(t, int32(3), finished.Load())
+	assert.True(t, periodic.IsExpired())
+	assert.Equal(t, int64(0), wheel.PendingTimeouts())
+	assert.Equal(t, workerStateShutdown, wheel.State())
+}
+
+func TestShutdown_ContextDone(t *testing.T) {
+	wheel, err := NewWheelTimer(time.Millisecond*10, 64)
+	assert.NoError(t, err)
+
+	timeout, err := wheel.NewTimeout(TimerTaskFunc(func(timeout Timeout) error { return nil }), time.Hour)
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+	unprocessed, err := wheel.Shutdown(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, []Timeout{timeout}, unprocessed)
+	assert.True(t, timeout.IsCancelled())
+}
+
+func TestStopAndWait(t *testing.T) {
+	wheel, err := NewWheelTimer(time.Millisecond*10, 64)
+	assert.NoError(t, err)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var finished atomic.Bool
+	running, err := wheel.NewTimeout(TimerTaskFunc(func(timeout Timeout) error {
+		close(started)
+		<-release
+		finished.Store(true)
+		return nil
+	}), time.Millisecond*10)
+	assert.NoError(t, err)
+	pending, err := wheel.NewTimeout(TimerTaskFunc(func(timeout Timeout) error { return nil }), time.Hour)
+	assert.NoError(t, err)
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*20)
+	defer cancel()
+	cancelled, err := wheel.StopAndWait(ctx)
+	assert.Equal(t, []Timeout{pending}, cancelled)
+	var runningErr *RunningTasksError
+	assert.ErrorAs(t, err, &runningErr)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, []Timeout{running}, runningErr.Running)
+
+	close(release)
+	cancelled, err = wheel.StopAndWait(context.Background())
+	assert.NoError(t, err)
+	assert.Empty(t, cancelled)
+	assert.True(t, finished.Load())
+}
+
+func TestTimeoutReset(t *testing.T) {
+	wheel, advance := newFakeClockWheel(t, time.Millisecond*10)
+	defer wheel.Stop()
+
+	var fired atomic.Int32
+	newTimeout := func(delay time.Duration) Timeout {
+		timeout, err := wheel.NewTimeout(TimerTaskFunc(func(timeout Timeout) error {
+			fired.Add(1)
+			return nil
+		}), delay)
+		assert.NoError(t, err)
+		return timeout
+	}
+
+	// reset before the timeout has left the timeouts ring buffer.
+	early := newTimeout(time.Millisecond * 100)
+	assert.True(t, early.Reset(time.Millisecond*10))
+	advance(time.Millisecond * 20)
+	assert.Equal(t, int32(1), fired.Load())
+	assert.False(t, early.Reset(time.Millisecond*10))
+
+	// reset a timeout which sits in a bucket, e.g. an idle timer being refreshed.
+	idle := newTimeout(time.Millisecond * 30)
+	for i := 0; i < 5; i++ {
+		advance(time.Millisecond * 20)
+		assert.True(t, idle.Reset(time.Millisecond*30))
+	}
+	assert.Equal(t, int32(1), fired.Load())
+	advance(time.Millisecond * 40)
+	assert.Equal(t, int32(2), fired.Load())
+	assert.True(t, idle.IsExpired())
+
+	cancelled := newTimeout(time.Millisecond * 30)
+	assert.True(t, cancelled.Cancel())
+	assert.False(t, cancelled.Reset(time.Millisecond))
+	advance(time.Millisecond * 10)
+	assert.Equal(t, int64(0), wheel.PendingTimeouts())
+}
+
+func TestTimeoutState(t *testing.T) {
+	wheel, advance := newFakeClockWheel(t, time.Millisecond*10)
+	defer wheel.Stop()
+
+	newTimeout := func(f func(timeout Timeout) error) Timeout {
+		timeout, err := wheel.NewTimeout(TimerTaskFunc(f), time.Millisecond*10)
+		assert.NoError(t, err)
+		assert.Equal(t, TimeoutStateScheduled, timeout.State())
+		return timeout
+	}
+
+	var running TimeoutState
+	succeeded := newTimeout(func(timeout Timeout) error {
+		running = timeout.State()
+		return nil
+	})
+	failed := newTimeout(func(timeout Timeout) error {
+		return fmt.Errorf("failed")
+	})
+	panicked := newTimeout(func(timeout Timeout) error {
+		panic("panicked")
+	})
+	cancelled := newTimeout(func(timeout Timeout) error {
+		return nil
+	})
+	assert.True(t, cancelled.Cancel())
+	advance(time.Millisecond * 20)
+
+	assert.Equal(t, TimeoutStateRunning, running)
+	assert.False(t, running.IsDone())
+
+	for _, tc := range []struct {
+		timeout Timeout
+		state   TimeoutState
+		expired bool
+	}{
+		{succeeded, TimeoutStateSucceeded, true},
+		{failed, TimeoutStateFailed, true},
+		{panicked, TimeoutStatePanicked, true},
+		{cancelled, TimeoutStateCancelled, false},
+	} {
+		t.Run(tc.state.String(), func(t *testing.T) {
+			assert.Equal(t, tc.state, tc.timeout.State())
+			assert.True(t, tc.timeout.State().IsDone())
+			assert.Equal(t, tc.expired, tc.timeout.IsExpired())
+		})
+	}
+
+	t.Run("periodic", func(t *testing.T) {
+		timeout, err := wheel.NewPeriodicTimeout(TimerTaskFunc(func(timeout Timeout) error {
+			return nil
+		}), time.Millisecond*10, time.Millisecond*10)
+		assert.NoError(t, err)
+		advance(time.Millisecond * 30)
+		assert.Equal(t, TimeoutStateScheduled, timeout.State())
+		assert.True(t, timeout.Cancel())
+		assert.Equal(t, TimeoutStateCancelled, timeout.State())
+	})
+}
+
+func TestErrorHandler(t *testing.T) {
+	type handled struct {
+		timeout Timeout
+		err     error
+	}
+	var errs []handled
+	wheel, advance := newFakeClockWheel(t, time.Millisecond*10, WithErrorHandler(func(timeout Timeout, err error) {
+		errs = append(errs, handled{timeout, err})
+	}), WithPanicHandler(func(interface{}) {}))
+	defer wheel.Stop()
+
+	errFailed := fmt.Errorf("failed")
+	failed, err := wheel.NewTimeout(TimerTaskFunc(func(timeout Timeout) error {
+		return errFailed
+	}), time.Millisecond*10)
+	assert.NoError(t, err)
+	_, err = wheel.NewTimeout(TimerTaskFunc(func(timeout Timeout) error {
+		panic("panicked")
+	}), time.Millisecond*10)
+	assert.NoError(t, err)
+	succeeded, err := wheel.NewTimeout(TimerTaskFunc(func(timeout Timeout) error {
+		return nil
+	}), time.Millisecond*10)
+	assert.NoError(t, err)
+	assert.NotEqual(t, failed.ID(), succeeded.ID())
+
+	advance(time.Millisecond * 20)
+	assert.Len(t, errs, 1)
+	assert.Same(t, failed, errs[0].timeout)
+	assert.ErrorIs(t, errs[0].err, errFailed)
+}
+
+func TestErrorHandler_Default(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	wheel, advance := newFakeClockWheel(t, time.Millisecond*10, WithLogger(logger))
+	defer wheel.Stop()
+
+	timeout, err := wheel.NewTimeout(TimerTaskFunc(func(timeout Timeout) error {
+		return fmt.Errorf("failed")
+	}), time.Millisecond*10)
+	assert.NoError(t, err)
+	advance(time.Millisecond * 20)
+
+	var record struct {
+		Msg       string        `json:"msg"`
+		Error     string        `json:"error"`
+		Task      string        `json:"task"`
+		TimeoutID uint64        `json:"timeout_id"`
+		Deadline  time.Time     `json:"deadline"`
+		RunAt     time.Time     `json:"run_at"`
+		Lateness  time.Duration `json:"lateness"`
+	}
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	assert.Equal(t, "[wheeltimer] task run error", record.Msg)
+	assert.Equal(t, "failed", record.Error)
+	assert.Equal(t, "wheeltimer.TimerTaskFunc", record.Task)
+	assert.Equal(t, timeout.ID(), record.TimeoutID)
+	start := wheel.startTime.Load().(time.Time)
+	assert.True(t, start.Add(time.Millisecond*10).Equal(record.Deadline))
+	assert.True(t, record.RunAt.After(record.Deadline))
+	assert.Equal(t, record.RunAt.Sub(record.Deadline), record.Lateness)
+}
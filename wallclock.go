@@ -0,0 +1,60 @@
+package wheeltimer
+
+import (
+	"time"
+)
+
+// NewTimeoutAt schedules the specified TimerTask for one-time execution at the wall clock instant t.
+// An instant in the past is run at the next tick.
+//
+// The deadlines of the timer are kept on the monotonic clock, so t is converted when the timeout is scheduled,
+// and later changes of the system clock are not followed unless they are detected with WithWallClockCheck.
+func (tw *WheelTimer) NewTimeoutAt(task TimerTask, t time.Time, opts ...TimeoutOption) (Timeout, error) {
+	timeout := newWheelTimeout(tw, task, 0)
+	timeout.apply(opts)
+	timeout.wallDeadline = t.UnixNano()
+	if err := tw.schedule(timeout, t.Round(0).Sub(tw.clock.Now().Round(0))); err != nil {
+		return nil, err
+	}
+	return timeout, nil
+}
+
+// checkWallClock measures the offset between the wall clock and the monotonic clock of the timer, and moves the
+// timeouts scheduled for a wall clock instant to their new deadlines if it has changed by more than the configured
+// threshold since the last check.
+func (tw *WheelTimer) checkWallClock() {
+	startTime := tw.startTime.Load().(time.Time)
+	skew := tw.clock.Now().Round(0).Sub(startTime.Round(0)) - tw.clock.Since(startTime)
+	if jump := skew - tw.skew; jump <= tw.wallClockThreshold && jump >= -tw.wallClockThreshold {
+		return
+	}
+	tw.skew = skew
+
+	var timeouts []*WheelTimeout
+	collect := func(wheel []*WheelBucket) {
+		for _, bucket := range wheel {
+			for timeout := bucket.head; timeout != nil; timeout = timeout.next {
+				if timeout.wallDeadline != 0 && !timeout.IsCancelled() {
+					timeouts = append(timeouts, timeout)
+				}
+			}
+		}
+	}
+	collect(tw.wheel)
+	for _, wheel := range tw.overflowWheels {
+		collect(wheel)
+	}
+
+	for _, timeout := range timeouts {
+		timeout.bucket.unlink(timeout)
+		timeout.setDeadline(tw.wallClockDeadline(timeout))
+		tw.addToBucket(timeout)
+	}
+}
+
+// wallClockDeadline converts the wall clock deadline of timeout to the monotonic clock of the timer, using the offset
+// measured by the last check.
+func (tw *WheelTimer) wallClockDeadline(timeout *WheelTimeout) time.Duration {
+	startWall := tw.startTime.Load().(time.Time).Round(0)
+	return time.Unix(0, timeout.wallDeadline).Sub(startWall) - tw.skew
+}
@@ -0,0 +1,73 @@
+package wheeltimer
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// jumpClock is a FakeClock whose wall clock can be set without moving its monotonic clock, which is measured
+// by the promoted FakeClock.Since.
+type jumpClock struct {
+	*FakeClock
+	lock   sync.Mutex
+	offset time.Duration
+}
+
+func (c *jumpClock) Now() time.Time {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.FakeClock.Now().Add(c.offset)
+}
+
+func (c *jumpClock) jump(d time.Duration) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.offset += d
+}
+
+func TestNewTimeoutAt(t *testing.T) {
+	clock := &jumpClock{FakeClock: NewFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))}
+	wheel, err := NewWheelTimer(time.Millisecond*10, 8, WithClock(clock), WithExecutor(inlineExecutor{}),
+		WithWallClockCheck(time.Millisecond*100))
+	assert.NoError(t, err)
+	defer wheel.Stop()
+	assert.NoError(t, wheel.Start())
+
+	advance := func(d time.Duration) {
+		for ; d > 0; d -= time.Millisecond * 10 {
+			clock.BlockUntil(1)
+			clock.Advance(time.Millisecond * 10)
+		}
+		clock.BlockUntil(1)
+	}
+
+	var past, soon, later atomic.Bool
+	newTimeoutAt := func(fired *atomic.Bool, at time.Time) {
+		_, err := wheel.NewTimeoutAt(TimerTaskFunc(func(timeout Timeout) error {
+			fired.Store(true)
+			return nil
+		}), at)
+		assert.NoError(t, err)
+	}
+	now := clock.Now()
+	newTimeoutAt(&past, now.Add(-time.Hour))
+	newTimeoutAt(&soon, now.Add(time.Millisecond*35))
+	newTimeoutAt(&later, now.Add(time.Hour))
+
+	// an instant in the past runs at the next tick.
+	advance(time.Millisecond * 10)
+	assert.True(t, past.Load())
+	assert.False(t, soon.Load())
+
+	advance(time.Millisecond * 30)
+	assert.True(t, soon.Load())
+
+	// the system clock is set one hour ahead, the timeout for an hour later is due now.
+	clock.jump(time.Hour)
+	advance(time.Millisecond * 20)
+	assert.True(t, later.Load())
+}